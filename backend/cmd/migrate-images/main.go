@@ -0,0 +1,169 @@
+// migrate-images は message_images.data に保存されている画像をBlobStoreへ移す
+//
+// 事前に db/migrations の 1, 2 を適用しておくこと。Blobのキーには内容のハッシュを使う。
+// content_hash が空の行はBlobStoreから読み直してハッシュを埋める。
+// 全件の移行後に -drop-data を付けて実行すると data カラムを削除する。
+//
+// 3 を適用した後は全ての画像が image_blobs に移っているので、-drop-data だけを行う。
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"io"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/traP-jp/h25s_09/repository"
+	"github.com/traP-jp/h25s_09/storage"
+)
+
+type legacyImage struct {
+	ID   uuid.UUID `db:"id"`
+	Data []byte    `db:"data"`
+	Mime string    `db:"mime"`
+}
+
+func main() {
+	batchSize := flag.Int("batch", 50, "一度に読み込む画像の数")
+	dropData := flag.Bool("drop-data", false, "移行完了後に message_images.data カラムを削除する")
+	flag.Parse()
+
+	db, err := repository.NewDB()
+	if err != nil {
+		log.Fatal("Failed to connect to the database: ", err)
+	}
+	defer db.Close()
+
+	hasData, err := columnExists(db, "message_images", "data")
+	if err != nil {
+		log.Fatal("Failed to inspect the schema: ", err)
+	}
+	if !hasData {
+		log.Print("message_images.data does not exist; nothing to migrate")
+		return
+	}
+	// migration 3 で blob_key は image_blobs に移る
+	hasBlobKey, err := columnExists(db, "message_images", "blob_key")
+	if err != nil {
+		log.Fatal("Failed to inspect the schema: ", err)
+	}
+
+	if *dropData {
+		if err := dropDataColumn(db, hasBlobKey); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if !hasBlobKey {
+		log.Print("images are already in image_blobs; run with -drop-data to drop message_images.data")
+		return
+	}
+
+	blobs, err := storage.NewBlobStore()
+	if err != nil {
+		log.Fatal("Failed to initialize blob store: ", err)
+	}
+
+	ctx := context.Background()
+	migrated := 0
+	for {
+		var images []legacyImage
+		err := db.Select(&images, "SELECT id, data, mime FROM message_images WHERE blob_key IS NULL AND data IS NOT NULL LIMIT ?", *batchSize)
+		if err != nil {
+			log.Fatal("Failed to select images: ", err)
+		}
+		if len(images) == 0 {
+			break
+		}
+		for _, img := range images {
+			// 投稿時と同じくハッシュをキーにするので、同じ内容の画像は1つのBlobになる
+			sum := sha256.Sum256(img.Data)
+			hash := hex.EncodeToString(sum[:])
+			if err := blobs.Put(ctx, hash, bytes.NewReader(img.Data), int64(len(img.Data)), img.Mime); err != nil {
+				log.Fatalf("Failed to put image %s: %v", img.ID, err)
+			}
+			_, err := db.Exec("UPDATE message_images SET blob_key = ?, content_hash = ?, size = ?, data = NULL WHERE id = ?", hash, hash, len(img.Data), img.ID)
+			if err != nil {
+				log.Fatalf("Failed to update image %s: %v", img.ID, err)
+			}
+			migrated++
+		}
+		log.Printf("migrated %d images", migrated)
+	}
+	log.Printf("done: migrated %d images", migrated)
+
+	if err := backfillHashes(ctx, db, blobs); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// backfillHashes は content_hash が空の画像のハッシュをBlobStoreの内容から計算する
+func backfillHashes(ctx context.Context, db *sqlx.DB, blobs storage.BlobStore) error {
+	var images []struct {
+		ID      uuid.UUID `db:"id"`
+		BlobKey string    `db:"blob_key"`
+	}
+	err := db.Select(&images, "SELECT id, blob_key FROM message_images WHERE content_hash = '' AND blob_key IS NOT NULL")
+	if err != nil {
+		return err
+	}
+	for _, img := range images {
+		blob, err := blobs.Get(ctx, img.BlobKey)
+		if err != nil {
+			return err
+		}
+		hasher := sha256.New()
+		_, err = io.Copy(hasher, blob)
+		blob.Close()
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec("UPDATE message_images SET content_hash = ? WHERE id = ?", hex.EncodeToString(hasher.Sum(nil)), img.ID)
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("done: hashed %d images", len(images))
+	return nil
+}
+
+// columnExists は接続先のデータベースの table に column があるかどうか
+func columnExists(db *sqlx.DB, table, column string) (bool, error) {
+	var count int
+	err := db.Get(&count, `SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`, table, column)
+	return count > 0, err
+}
+
+func dropDataColumn(db *sqlx.DB, hasBlobKey bool) error {
+	if !hasBlobKey {
+		// migration 3 の後は、全ての画像の content_hash が image_blobs を指している
+		if _, err := db.Exec("ALTER TABLE message_images DROP COLUMN data"); err != nil {
+			return err
+		}
+		log.Print("dropped message_images.data")
+		return nil
+	}
+
+	var remaining int
+	if err := db.Get(&remaining, "SELECT COUNT(*) FROM message_images WHERE blob_key IS NULL"); err != nil {
+		return err
+	}
+	if remaining > 0 {
+		log.Fatalf("%d images are not migrated yet", remaining)
+	}
+	_, err := db.Exec(`ALTER TABLE message_images
+		DROP COLUMN data,
+		MODIFY COLUMN blob_key VARCHAR(255) NOT NULL,
+		ALTER COLUMN size DROP DEFAULT`)
+	if err != nil {
+		return err
+	}
+	log.Print("dropped message_images.data")
+	return nil
+}
@@ -0,0 +1,25 @@
+// reindex-search はメッセージの全文検索用インデックス(messages.search_ngrams)を作り直す
+//
+// db/migrations/4_messages_search_ngrams.sql を適用した後や、
+// 分割の方法(repository/ngram.go)を変えたときに実行する。
+package main
+
+import (
+	"log"
+
+	"github.com/traP-jp/h25s_09/repository"
+)
+
+func main() {
+	db, err := repository.NewDB()
+	if err != nil {
+		log.Fatal("Failed to connect to the database: ", err)
+	}
+	defer db.Close()
+
+	updated, err := repository.NewRepository(db).RebuildSearchIndex()
+	if err != nil {
+		log.Fatalf("Failed to rebuild search index (updated %d messages): %v", updated, err)
+	}
+	log.Printf("done: updated %d messages", updated)
+}
@@ -0,0 +1,149 @@
+// seed は開発用のデータベースに、複数のユーザーの投稿・返信・リアクション・フォローを作る
+//
+// 作ったユーザーには開発用ログイン (/api/dev/login や ?devUser=alice) でなりすませる。
+// 本番のデータベースに入れないよう、ENVIRONMENT=development のときだけ動く。
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/traP-jp/h25s_09/domain"
+	"github.com/traP-jp/h25s_09/handler/middleware"
+	"github.com/traP-jp/h25s_09/repository"
+)
+
+type seedUser struct {
+	name        string
+	displayName string
+	bio         string
+}
+
+var users = []seedUser{
+	{"alice", "Alice", "バックエンド担当。Go が好き"},
+	{"bob", "Bob", "フロントエンドをやっています"},
+	{"carol", "Carol", "デザインとお絵描き"},
+	{"dave", "Dave", "インフラと自宅サーバー"},
+	{"eve", "Eve", "セキュリティに興味があります"},
+	{"frank", "Frank", "競プロ勢"},
+	{"grace", "Grace", "ゲーム制作班"},
+	{"heidi", "Heidi", "新入生です、よろしくお願いします"},
+}
+
+var posts = []string{
+	"おはようございます #朝",
+	"今日のランチはカレーでした #ごはん",
+	"Go のジェネリクス便利すぎる #golang",
+	"ハッカソン進捗どうですか？ #hackathon",
+	"バグが直らない… #つらい",
+	"新しいキーボード買った！ #自作キーボード",
+	"部室にお菓子置いておきました",
+	"デプロイ完了 🎉 #hackathon",
+	"眠い #朝",
+	"README 更新しました",
+	"今日は早めに帰ります",
+}
+
+// mentionPost は他のユーザーへのメンションを含む投稿
+const mentionPost = "@%s これ見ました？"
+
+var replies = []string{
+	"わかる",
+	"いいですね！",
+	"それな",
+	"あとで見ます",
+	"手伝いましょうか？",
+	"おつかれさまです",
+	"😂",
+}
+
+func main() {
+	postsPerUser := flag.Int("posts", 5, "number of posts per user")
+	replyRate := flag.Float64("replies", 0.5, "probability that each user replies to a post")
+	reactionRate := flag.Float64("reactions", 0.4, "probability that each user reacts to a post")
+	seed := flag.Uint64("seed", 1, "random seed")
+	flag.Parse()
+
+	if os.Getenv("ENVIRONMENT") != middleware.DevelopmentEnv {
+		log.Fatal("seed can only be run with ENVIRONMENT=development")
+	}
+
+	db, err := repository.NewDB()
+	if err != nil {
+		log.Fatal("Failed to connect to the database: ", err)
+	}
+	defer db.Close()
+	repo := repository.NewRepository(db)
+	rnd := rand.New(rand.NewPCG(*seed, *seed))
+
+	for _, u := range users {
+		if err := repo.EnsureUser(u.name); err != nil {
+			log.Fatalf("Failed to create user %s: %v", u.name, err)
+		}
+		if _, err := repo.UpdateUserProfile(u.name, u.displayName, u.bio); err != nil {
+			log.Fatalf("Failed to update profile of %s: %v", u.name, err)
+		}
+	}
+
+	follows := 0
+	for _, follower := range users {
+		for _, followee := range users {
+			if follower.name == followee.name || rnd.Float64() > 0.5 {
+				continue
+			}
+			err := repo.InsertFollow(follower.name, followee.name)
+			if err != nil && !errors.Is(err, domain.ErrConflict) {
+				log.Fatalf("Failed to follow %s -> %s: %v", follower.name, followee.name, err)
+			}
+			follows++
+		}
+	}
+
+	var created []*domain.Message
+	for _, u := range users {
+		for range *postsPerUser {
+			content := posts[rnd.IntN(len(posts))]
+			if rnd.Float64() < 0.3 {
+				content = fmt.Sprintf(mentionPost, users[rnd.IntN(len(users))].name)
+			}
+			msg, err := repo.CreateMessage(u.name, content, uuid.Nil, uuid.Nil, time.Time{}, uuid.Nil, nil)
+			if err != nil {
+				log.Fatalf("Failed to create message: %v", err)
+			}
+			created = append(created, msg)
+		}
+	}
+
+	replyCount, reactionCount := 0, 0
+	for _, msg := range created {
+		for _, u := range users {
+			if u.name == msg.Author {
+				continue
+			}
+			if rnd.Float64() < *replyRate {
+				_, err := repo.CreateMessage(u.name, replies[rnd.IntN(len(replies))], msg.ID, uuid.Nil, time.Time{}, uuid.Nil, nil)
+				if err != nil {
+					log.Fatalf("Failed to create reply: %v", err)
+				}
+				replyCount++
+			}
+			if rnd.Float64() < *reactionRate {
+				_, err := repo.InsertMessageReaction(msg.ID, u.name)
+				if err != nil && !errors.Is(err, domain.ErrConflict) {
+					log.Fatalf("Failed to add reaction: %v", err)
+				}
+				reactionCount++
+			}
+		}
+	}
+
+	log.Printf("done: %d users, %d follows, %d posts, %d replies, %d reactions",
+		len(users), follows, len(created), replyCount, reactionCount)
+}
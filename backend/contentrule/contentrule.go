@@ -0,0 +1,159 @@
+// contentrule はメッセージ本文が投稿のルールを満たしているかを調べる
+//
+// ルールは環境変数で設定する。
+//
+//	CONTENT_MAX_LENGTH       本文の最大文字数(rune 数)。MaxLength 以下
+//	CONTENT_MAX_LINKS        本文に含められる URL の数
+//	CONTENT_BANNED_WORDS     禁止する語句(カンマ区切り)。大文字と小文字は区別しない
+//	CONTENT_BANNED_PATTERNS  禁止する正規表現(空白区切り)。空白を含めたい場合は \s と書く
+package contentrule
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxLength は messages.message (TEXT, 65535 バイト) にどの文字でも収まる文字数
+	MaxLength = 65535 / utf8.UTFMax
+
+	DefaultMaxLinks = 5
+
+	// MaxJoiners はゼロ幅接合子・非接合子を含められる数。絵文字の合成に使うので少しは許す
+	MaxJoiners = 10
+)
+
+// 違反したルールの名前。レスポンスの rule に入る
+const (
+	RuleMaxLength         = "max_length"
+	RuleMaxLinks          = "max_links"
+	RuleBannedWord        = "banned_word"
+	RuleBannedPattern     = "banned_pattern"
+	RuleControlCharacters = "control_characters"
+	RuleZeroWidth         = "zero_width"
+)
+
+// Violation は本文が満たしていないルール
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+func (v *Violation) Error() string {
+	return v.Rule + ": " + v.Message
+}
+
+type Rules struct {
+	MaxLength      int
+	MaxLinks       int
+	BannedWords    []string
+	BannedPatterns []*regexp.Regexp
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://`)
+
+// Default は禁止語句のない既定のルール
+func Default() *Rules {
+	return &Rules{
+		MaxLength: MaxLength,
+		MaxLinks:  DefaultMaxLinks,
+	}
+}
+
+// NewFromEnv は環境変数からルールを読む
+func NewFromEnv() (*Rules, error) {
+	rules := Default()
+
+	maxLength, err := strconv.Atoi(cmp.Or(os.Getenv("CONTENT_MAX_LENGTH"), strconv.Itoa(MaxLength)))
+	if err != nil || maxLength <= 0 || maxLength > MaxLength {
+		return nil, fmt.Errorf("CONTENT_MAX_LENGTH must be 1 to %d", MaxLength)
+	}
+	rules.MaxLength = maxLength
+
+	maxLinks, err := strconv.Atoi(cmp.Or(os.Getenv("CONTENT_MAX_LINKS"), strconv.Itoa(DefaultMaxLinks)))
+	if err != nil || maxLinks < 0 {
+		return nil, fmt.Errorf("CONTENT_MAX_LINKS must be a non-negative integer")
+	}
+	rules.MaxLinks = maxLinks
+
+	for _, word := range strings.Split(os.Getenv("CONTENT_BANNED_WORDS"), ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			rules.BannedWords = append(rules.BannedWords, strings.ToLower(word))
+		}
+	}
+	for _, expr := range strings.Fields(os.Getenv("CONTENT_BANNED_PATTERNS")) {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONTENT_BANNED_PATTERNS %q: %w", expr, err)
+		}
+		rules.BannedPatterns = append(rules.BannedPatterns, re)
+	}
+	return rules, nil
+}
+
+// Check は本文がルールを満たしていなければ最初に見つかった違反を返す
+func (r *Rules) Check(content string) *Violation {
+	if !utf8.ValidString(content) {
+		return &Violation{Rule: RuleControlCharacters, Message: "message is not valid UTF-8"}
+	}
+	if n := utf8.RuneCountInString(content); n > r.MaxLength {
+		return &Violation{Rule: RuleMaxLength, Message: fmt.Sprintf("message must be at most %d characters (got %d)", r.MaxLength, n)}
+	}
+
+	joiners := 0
+	for _, c := range content {
+		switch {
+		case c == '\n' || c == '\r' || c == '\t':
+		case unicode.IsControl(c):
+			return &Violation{Rule: RuleControlCharacters, Message: fmt.Sprintf("message must not contain control character %U", c)}
+		case c == '\u200c' || c == '\u200d':
+			joiners++
+			if joiners > MaxJoiners {
+				return &Violation{Rule: RuleZeroWidth, Message: fmt.Sprintf("message must not contain more than %d zero-width joiners", MaxJoiners)}
+			}
+		case isInvisible(c):
+			return &Violation{Rule: RuleZeroWidth, Message: fmt.Sprintf("message must not contain invisible character %U", c)}
+		}
+	}
+
+	if n := len(linkPattern.FindAllStringIndex(content, -1)); n > r.MaxLinks {
+		return &Violation{Rule: RuleMaxLinks, Message: fmt.Sprintf("message must contain at most %d links (got %d)", r.MaxLinks, n)}
+	}
+
+	lower := strings.ToLower(content)
+	for _, word := range r.BannedWords {
+		if strings.Contains(lower, word) {
+			return &Violation{Rule: RuleBannedWord, Message: "message contains a banned word"}
+		}
+	}
+	for _, re := range r.BannedPatterns {
+		if re.MatchString(content) {
+			return &Violation{Rule: RuleBannedPattern, Message: "message matches a banned pattern"}
+		}
+	}
+	return nil
+}
+
+// isInvisible はゼロ幅スペースや文字の向きを変える制御文字など、見えないのに本文に入れられる文字かどうか
+// 絵文字の異体字セレクタは見た目を変えるので許す
+func isInvisible(c rune) bool {
+	switch {
+	case c == '\u00ad', c == '\u180e':
+		return true
+	case c >= '\u200b' && c <= '\u200f':
+		return true
+	case c >= '\u202a' && c <= '\u202e':
+		return true
+	case c >= '\u2060' && c <= '\u206f':
+		return true
+	case c == '\ufeff':
+		return true
+	}
+	return false
+}
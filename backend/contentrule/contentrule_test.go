@@ -0,0 +1,97 @@
+package contentrule
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	rules := &Rules{
+		MaxLength:      20,
+		MaxLinks:       1,
+		BannedWords:    []string{"spam"},
+		BannedPatterns: []*regexp.Regexp{regexp.MustCompile(`\d{4}-\d{4}`)},
+	}
+	family := "\U0001f468\u200d\U0001f469\u200d\U0001f467\u200d\U0001f466" // 3つのゼロ幅接合子で合成される絵文字
+
+	tests := []struct {
+		name    string
+		content string
+		want    string // 違反したルール。満たしていれば空文字列
+	}{
+		{"plain", "hello", ""},
+		{"newline and tab", "a\nb\r\n\tc", ""},
+		{"max length", strings.Repeat("あ", 20), ""},
+		{"too long", strings.Repeat("あ", 21), RuleMaxLength},
+		{"one link", "see https://x.jp", ""},
+		{"too many links", "http://a HTTPS://b", RuleMaxLinks},
+		{"banned word", "buy SPAM now", RuleBannedWord},
+		{"banned pattern", "call 1234-5678", RuleBannedPattern},
+		{"control character", "a\x07b", RuleControlCharacters},
+		{"invalid UTF-8", "a\xffb", RuleControlCharacters},
+		{"zero-width space", "a\u200bb", RuleZeroWidth},
+		{"bidi override", "a\u202eb", RuleZeroWidth},
+		{"byte order mark", "\ufeffa", RuleZeroWidth},
+		{"emoji with joiners", family, ""},
+		{"variation selector", "\u263a\ufe0f", ""},
+		{"joiners up to limit", strings.Repeat("a\u200d", MaxJoiners), ""},
+		{"too many joiners", strings.Repeat("\u200d", MaxJoiners+1), RuleZeroWidth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := rules.Check(tt.content)
+			got := ""
+			if v != nil {
+				got = v.Rule
+			}
+			if got != tt.want {
+				t.Fatalf("Check(%q) = %v, want rule %q", tt.content, v, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{"defaults", nil, false},
+		{"all set", map[string]string{
+			"CONTENT_MAX_LENGTH":      "280",
+			"CONTENT_MAX_LINKS":       "0",
+			"CONTENT_BANNED_WORDS":    "foo, Bar ,",
+			"CONTENT_BANNED_PATTERNS": `foo\s+bar ^x`,
+		}, false},
+		{"zero max length", map[string]string{"CONTENT_MAX_LENGTH": "0"}, true},
+		{"max length over limit", map[string]string{"CONTENT_MAX_LENGTH": "100000"}, true},
+		{"max length not a number", map[string]string{"CONTENT_MAX_LENGTH": "many"}, true},
+		{"negative max links", map[string]string{"CONTENT_MAX_LINKS": "-1"}, true},
+		{"invalid pattern", map[string]string{"CONTENT_BANNED_PATTERNS": "("}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"CONTENT_MAX_LENGTH", "CONTENT_MAX_LINKS", "CONTENT_BANNED_WORDS", "CONTENT_BANNED_PATTERNS"} {
+				t.Setenv(key, tt.env[key])
+			}
+			rules, err := NewFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil || tt.env == nil {
+				return
+			}
+			if rules.MaxLength != 280 || rules.MaxLinks != 0 {
+				t.Fatalf("MaxLength, MaxLinks = %d, %d, want 280, 0", rules.MaxLength, rules.MaxLinks)
+			}
+			if got := strings.Join(rules.BannedWords, ","); got != "foo,bar" {
+				t.Fatalf("BannedWords = %q, want foo,bar", got)
+			}
+			if len(rules.BannedPatterns) != 2 || rules.Check("foo \t bar") == nil {
+				t.Fatalf("BannedPatterns = %v", rules.BannedPatterns)
+			}
+		})
+	}
+}
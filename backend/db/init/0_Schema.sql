@@ -1,22 +1,49 @@
+CREATE TABLE users (
+    username     VARCHAR(32)  PRIMARY KEY,
+    display_name VARCHAR(32)  NOT NULL DEFAULT '',
+    bio          VARCHAR(160) NOT NULL DEFAULT '',
+    role         VARCHAR(16)  NOT NULL DEFAULT 'user', -- user / moderator / admin
+    suspended_until DATETIME  DEFAULT NULL, -- 投稿停止の期限
+    created_at   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
 CREATE TABLE messages (
     id          CHAR(36)    PRIMARY KEY,
     author      VARCHAR(32) NOT NULL,
     message     TEXT        NOT NULL,
     replies_to  CHAR(36)    DEFAULT NULL,
+    quote_of    CHAR(36)    DEFAULT NULL,
+    status      VARCHAR(16) NOT NULL DEFAULT 'published', -- published / scheduled / canceled / hidden
+    publish_at  DATETIME    DEFAULT NULL, -- 予約投稿の公開日時
+    token_id    CHAR(36)    DEFAULT NULL, -- トークンで投稿した場合のトークンID
+    search_ngrams MEDIUMTEXT NOT NULL, -- 全文検索用に本文をbi-gramに分割したもの (repository/ngram.go)
     created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     INDEX idx_author (author),
     INDEX idx_replies_to (replies_to),
-    INDEX idx_created_at (created_at)
+    INDEX idx_quote_of (quote_of),
+    INDEX idx_created_at (created_at),
+    INDEX idx_status_publish_at (status, publish_at),
+    FULLTEXT INDEX idx_search_ngrams (search_ngrams)
+);
+
+CREATE TABLE image_blobs (
+    hash        CHAR(64)     PRIMARY KEY,
+    blob_key    VARCHAR(255) NOT NULL,
+    mime        VARCHAR(64)  NOT NULL,
+    size        BIGINT       NOT NULL,
+    ref_count   INT          NOT NULL DEFAULT 0,
+    created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE TABLE message_images (
     id CHAR(36) PRIMARY KEY,
     message_id  CHAR(36)    NOT NULL,
-    data        MEDIUMBLOB  NOT NULL,
-    mime        VARCHAR(64) NOT NULL,
+    content_hash CHAR(64)   NOT NULL,
     created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (message_id) REFERENCES messages(id),
+    FOREIGN KEY (content_hash) REFERENCES image_blobs(hash),
     INDEX idx_message_id (message_id)
 );
 
@@ -28,8 +55,166 @@ CREATE TABLE message_reactions (
     INDEX idx_message_id (message_id)
 );
 
+CREATE TABLE message_tags (
+    message_id  CHAR(36)    NOT NULL,
+    tag         VARCHAR(64) NOT NULL,
+    position    INT         NOT NULL, -- 本文中で何番目に出てきたか
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (message_id, tag),
+    FOREIGN KEY (message_id) REFERENCES messages(id),
+    INDEX idx_tag_created_at (tag, created_at),
+    INDEX idx_created_at (created_at)
+);
+
+CREATE TABLE message_mentions (
+    message_id  CHAR(36)    NOT NULL,
+    username    VARCHAR(32) NOT NULL,
+    `offset`    INT         NOT NULL, -- 本文中の位置(文字数)
+    length      INT         NOT NULL,
+    PRIMARY KEY (message_id, `offset`),
+    FOREIGN KEY (message_id) REFERENCES messages(id),
+    INDEX idx_username (username)
+);
+
+CREATE TABLE notifications (
+    id          CHAR(36)    PRIMARY KEY,
+    username    VARCHAR(32) NOT NULL, -- 通知を受け取るユーザー
+    type        VARCHAR(16) NOT NULL, -- reply, reaction, mention
+    actor       VARCHAR(32) NOT NULL,
+    message_id  CHAR(36)    NOT NULL,
+    is_read     BOOLEAN     NOT NULL DEFAULT FALSE,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    unread      TINYINT     AS (IF(is_read, NULL, 1)) PERSISTENT, -- 同じ操作の未読の通知を1件にまとめるための列
+    FOREIGN KEY (message_id) REFERENCES messages(id),
+    INDEX idx_username_created_at (username, created_at),
+    INDEX idx_username_is_read (username, is_read),
+    UNIQUE INDEX idx_unread_notification (username, type, actor, message_id, unread)
+);
+
+CREATE TABLE follows (
+    follower    VARCHAR(32) NOT NULL,
+    followee    VARCHAR(32) NOT NULL,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower, followee),
+    INDEX idx_followee (followee)
+);
+
+CREATE TABLE mutes (
+    muter       VARCHAR(32) NOT NULL,
+    mutee       VARCHAR(32) NOT NULL,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (muter, mutee)
+);
+
+CREATE TABLE blocks (
+    blocker     VARCHAR(32) NOT NULL,
+    blocked     VARCHAR(32) NOT NULL,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (blocker, blocked),
+    INDEX idx_blocked (blocked)
+);
+
+CREATE TABLE reposts (
+    message_id  CHAR(36)    NOT NULL,
+    username    VARCHAR(32) NOT NULL,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (message_id, username),
+    FOREIGN KEY (message_id) REFERENCES messages(id),
+    INDEX idx_username_created_at (username, created_at)
+);
+
+CREATE TABLE bookmarks (
+    message_id  CHAR(36)    NOT NULL,
+    username    VARCHAR(32) NOT NULL,
+    created_at  DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (message_id, username),
+    FOREIGN KEY (message_id) REFERENCES messages(id),
+    INDEX idx_username_created_at (username, created_at, message_id)
+);
+
+CREATE TABLE pinned_messages (
+    message_id  CHAR(36)    NOT NULL,
+    username    VARCHAR(32) NOT NULL,
+    pinned_at   DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (message_id),
+    FOREIGN KEY (message_id) REFERENCES messages(id),
+    INDEX idx_username_pinned_at (username, pinned_at)
+);
+
+CREATE TABLE polls (
+    message_id  CHAR(36)    NOT NULL PRIMARY KEY,
+    multiple    BOOLEAN     NOT NULL DEFAULT FALSE,
+    expires_at  DATETIME    NOT NULL,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (message_id) REFERENCES messages(id)
+);
+
+CREATE TABLE poll_options (
+    message_id  CHAR(36)    NOT NULL,
+    option_index TINYINT    NOT NULL,
+    label       VARCHAR(64) NOT NULL,
+    PRIMARY KEY (message_id, option_index),
+    FOREIGN KEY (message_id) REFERENCES polls(message_id)
+);
+
+-- 1人1回しか投票できないように、投票ごとに1行作る
+CREATE TABLE poll_ballots (
+    message_id  CHAR(36)    NOT NULL,
+    username    VARCHAR(32) NOT NULL,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (message_id, username),
+    FOREIGN KEY (message_id) REFERENCES polls(message_id)
+);
+
+-- 投票で選んだ選択肢。複数選択の場合は1回の投票で複数行になる
+CREATE TABLE poll_votes (
+    message_id  CHAR(36)    NOT NULL,
+    username    VARCHAR(32) NOT NULL,
+    option_index TINYINT    NOT NULL,
+    PRIMARY KEY (message_id, username, option_index),
+    FOREIGN KEY (message_id, username) REFERENCES poll_ballots(message_id, username),
+    FOREIGN KEY (message_id, option_index) REFERENCES poll_options(message_id, option_index)
+);
+
+CREATE TABLE personal_access_tokens (
+    id           CHAR(36)    NOT NULL PRIMARY KEY,
+    username     VARCHAR(32) NOT NULL,
+    name         VARCHAR(64) NOT NULL,
+    token_hash   CHAR(64)    NOT NULL, -- トークンの SHA-256 (hex)
+    scopes       VARCHAR(64) NOT NULL, -- カンマ区切り
+    created_at   DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME    DEFAULT NULL,
+    revoked_at   DATETIME    DEFAULT NULL,
+    UNIQUE INDEX idx_token_hash (token_hash),
+    INDEX idx_username (username)
+);
+
+CREATE TABLE message_reports (
+    id          CHAR(36)     NOT NULL PRIMARY KEY,
+    message_id  CHAR(36)     NOT NULL,
+    reporter    VARCHAR(32)  NOT NULL,
+    reason      VARCHAR(200) NOT NULL,
+    resolved_at DATETIME     DEFAULT NULL,
+    created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX idx_message_id_reporter (message_id, reporter),
+    INDEX idx_resolved_at (resolved_at),
+    FOREIGN KEY (message_id) REFERENCES messages(id)
+);
+
+CREATE TABLE moderation_logs (
+    id              CHAR(36)     NOT NULL PRIMARY KEY,
+    moderator       VARCHAR(32)  NOT NULL,
+    action          VARCHAR(32)  NOT NULL,
+    message_id      CHAR(36)     DEFAULT NULL,
+    target_user     VARCHAR(32)  NOT NULL DEFAULT '',
+    suspended_until DATETIME     DEFAULT NULL,
+    note            VARCHAR(200) NOT NULL DEFAULT '',
+    created_at      DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_created_at (created_at)
+);
+
 CREATE TABLE achievements (
-    id          INT         NOT NULL,
+    name        VARCHAR(32) NOT NULL,
     username    VARCHAR(32) NOT NULL,
     achieved_at DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
     INDEX idx_username (username),
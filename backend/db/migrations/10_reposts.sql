@@ -0,0 +1,13 @@
+-- 引用投稿とリポスト
+ALTER TABLE messages
+    ADD COLUMN quote_of CHAR(36) DEFAULT NULL AFTER replies_to,
+    ADD INDEX idx_quote_of (quote_of);
+
+CREATE TABLE reposts (
+    message_id  CHAR(36)    NOT NULL,
+    username    VARCHAR(32) NOT NULL,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (message_id, username),
+    FOREIGN KEY (message_id) REFERENCES messages(id),
+    INDEX idx_username_created_at (username, created_at)
+);
@@ -0,0 +1,9 @@
+-- ブックマーク
+CREATE TABLE bookmarks (
+    message_id  CHAR(36)    NOT NULL,
+    username    VARCHAR(32) NOT NULL,
+    created_at  DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (message_id, username),
+    FOREIGN KEY (message_id) REFERENCES messages(id),
+    INDEX idx_username_created_at (username, created_at, message_id)
+);
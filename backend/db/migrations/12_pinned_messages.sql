@@ -0,0 +1,9 @@
+-- プロフィールに固定するメッセージ
+CREATE TABLE pinned_messages (
+    message_id  CHAR(36)    NOT NULL,
+    username    VARCHAR(32) NOT NULL,
+    pinned_at   DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (message_id),
+    FOREIGN KEY (message_id) REFERENCES messages(id),
+    INDEX idx_username_pinned_at (username, pinned_at)
+);
@@ -0,0 +1,5 @@
+-- 予約投稿
+ALTER TABLE messages
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'published' AFTER quote_of,
+    ADD COLUMN publish_at DATETIME DEFAULT NULL AFTER status,
+    ADD INDEX idx_status_publish_at (status, publish_at);
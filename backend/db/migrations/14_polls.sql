@@ -0,0 +1,35 @@
+-- 投票
+CREATE TABLE polls (
+    message_id  CHAR(36)    NOT NULL PRIMARY KEY,
+    multiple    BOOLEAN     NOT NULL DEFAULT FALSE,
+    expires_at  DATETIME    NOT NULL,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (message_id) REFERENCES messages(id)
+);
+
+CREATE TABLE poll_options (
+    message_id  CHAR(36)    NOT NULL,
+    option_index TINYINT    NOT NULL,
+    label       VARCHAR(64) NOT NULL,
+    PRIMARY KEY (message_id, option_index),
+    FOREIGN KEY (message_id) REFERENCES polls(message_id)
+);
+
+-- 1人1回しか投票できないように、投票ごとに1行作る
+CREATE TABLE poll_ballots (
+    message_id  CHAR(36)    NOT NULL,
+    username    VARCHAR(32) NOT NULL,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (message_id, username),
+    FOREIGN KEY (message_id) REFERENCES polls(message_id)
+);
+
+-- 投票で選んだ選択肢。複数選択の場合は1回の投票で複数行になる
+CREATE TABLE poll_votes (
+    message_id  CHAR(36)    NOT NULL,
+    username    VARCHAR(32) NOT NULL,
+    option_index TINYINT    NOT NULL,
+    PRIMARY KEY (message_id, username, option_index),
+    FOREIGN KEY (message_id, username) REFERENCES poll_ballots(message_id, username),
+    FOREIGN KEY (message_id, option_index) REFERENCES poll_options(message_id, option_index)
+);
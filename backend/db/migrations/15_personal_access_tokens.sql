@@ -0,0 +1,17 @@
+-- ボットやスクリプト用のトークン
+CREATE TABLE personal_access_tokens (
+    id           CHAR(36)    NOT NULL PRIMARY KEY,
+    username     VARCHAR(32) NOT NULL,
+    name         VARCHAR(64) NOT NULL,
+    token_hash   CHAR(64)    NOT NULL, -- トークンの SHA-256 (hex)
+    scopes       VARCHAR(64) NOT NULL, -- カンマ区切り
+    created_at   DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME    DEFAULT NULL,
+    revoked_at   DATETIME    DEFAULT NULL,
+    UNIQUE INDEX idx_token_hash (token_hash),
+    INDEX idx_username (username)
+);
+
+-- トークンで投稿されたメッセージはそのトークンのIDを持つ
+ALTER TABLE messages
+    ADD COLUMN token_id CHAR(36) DEFAULT NULL AFTER publish_at;
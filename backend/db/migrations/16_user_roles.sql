@@ -0,0 +1,3 @@
+-- ユーザーの権限 (user / moderator / admin)
+ALTER TABLE users
+    ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user' AFTER bio;
@@ -0,0 +1,28 @@
+-- 通報とモデレーションの記録
+CREATE TABLE message_reports (
+    id          CHAR(36)     NOT NULL PRIMARY KEY,
+    message_id  CHAR(36)     NOT NULL,
+    reporter    VARCHAR(32)  NOT NULL,
+    reason      VARCHAR(200) NOT NULL,
+    resolved_at DATETIME     DEFAULT NULL,
+    created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX idx_message_id_reporter (message_id, reporter),
+    INDEX idx_resolved_at (resolved_at),
+    FOREIGN KEY (message_id) REFERENCES messages(id)
+);
+
+CREATE TABLE moderation_logs (
+    id              CHAR(36)     NOT NULL PRIMARY KEY,
+    moderator       VARCHAR(32)  NOT NULL,
+    action          VARCHAR(32)  NOT NULL,
+    message_id      CHAR(36)     DEFAULT NULL,
+    target_user     VARCHAR(32)  NOT NULL DEFAULT '',
+    suspended_until DATETIME     DEFAULT NULL,
+    note            VARCHAR(200) NOT NULL DEFAULT '',
+    created_at      DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_created_at (created_at)
+);
+
+-- 投稿停止の期限
+ALTER TABLE users
+    ADD COLUMN suspended_until DATETIME DEFAULT NULL AFTER role;
@@ -0,0 +1,15 @@
+-- ユーザーのミュートとブロック
+CREATE TABLE mutes (
+    muter       VARCHAR(32) NOT NULL,
+    mutee       VARCHAR(32) NOT NULL,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (muter, mutee)
+);
+
+CREATE TABLE blocks (
+    blocker     VARCHAR(32) NOT NULL,
+    blocked     VARCHAR(32) NOT NULL,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (blocker, blocked),
+    INDEX idx_blocked (blocked)
+);
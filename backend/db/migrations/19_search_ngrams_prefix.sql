@@ -0,0 +1,6 @@
+-- 全文検索のトークンに接頭辞 x を付ける (repository/ngram.go)
+-- ASCIIの1文字のトークンが innodb_ft_min_token_size より短く、インデックスされていなかった
+-- go run ./cmd/reindex-search で作り直しても同じ結果になる
+UPDATE messages
+    SET search_ngrams = REGEXP_REPLACE(search_ngrams, '(^| )', '\\1x'), updated_at = updated_at
+    WHERE search_ngrams <> '';
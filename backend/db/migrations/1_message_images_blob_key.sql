@@ -0,0 +1,8 @@
+-- message_images.data (MEDIUMBLOB) から BlobStore へ画像を移すためのマイグレーション
+-- 1. このSQLを実行する
+-- 2. go run ./cmd/migrate-images で既存の画像をBlobStoreへ移す
+-- 3. go run ./cmd/migrate-images -drop-data で data カラムを削除する
+ALTER TABLE message_images
+    ADD COLUMN blob_key VARCHAR(255) DEFAULT NULL AFTER message_id,
+    ADD COLUMN size     BIGINT       NOT NULL DEFAULT 0 AFTER mime,
+    MODIFY COLUMN data  MEDIUMBLOB   DEFAULT NULL;
@@ -0,0 +1,12 @@
+-- 同じ操作の未読の通知は1件にまとめる (リアクションの付け外しを繰り返しても通知が増えないように)
+-- 既に重複している未読の通知は最新のものだけを残す
+DELETE n FROM notifications n
+    JOIN notifications newer
+        ON newer.username = n.username AND newer.type = n.type AND newer.actor = n.actor AND newer.message_id = n.message_id
+        AND newer.is_read = FALSE AND (newer.created_at, newer.id) > (n.created_at, n.id)
+    WHERE n.is_read = FALSE;
+
+-- 既読の通知は NULL になり、一意制約の対象から外れる
+ALTER TABLE notifications
+    ADD COLUMN unread TINYINT AS (IF(is_read, NULL, 1)) PERSISTENT,
+    ADD UNIQUE INDEX idx_unread_notification (username, type, actor, message_id, unread);
@@ -0,0 +1,2 @@
+-- トークンを使わずに投稿したメッセージの token_id に uuid.Nil が入っていたので NULL に直す
+UPDATE messages SET token_id = NULL, updated_at = updated_at WHERE token_id = '00000000-0000-0000-0000-000000000000';
@@ -0,0 +1,3 @@
+-- リポジトリは実績を名前で扱うが、テーブルには整数の id しかなく、実績の追加・取り消しが失敗していた
+-- 既存の id は実績の種類なので、そのまま文字列の名前にする
+ALTER TABLE achievements CHANGE id name VARCHAR(32) NOT NULL;
@@ -0,0 +1,4 @@
+-- 画像配信のETagに使う内容のハッシュ(SHA-256)を保存するカラム
+-- 既存の行は go run ./cmd/migrate-images で埋める
+ALTER TABLE message_images
+    ADD COLUMN content_hash CHAR(64) NOT NULL DEFAULT '' AFTER blob_key;
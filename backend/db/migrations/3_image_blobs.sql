@@ -0,0 +1,24 @@
+-- 同じ内容の画像を1つのBlobにまとめるためのマイグレーション
+-- go run ./cmd/migrate-images で全ての画像の blob_key と content_hash が埋まってから実行すること
+-- (これ以降の cmd/migrate-images は -drop-data で message_images.data を削除するだけになる)
+-- 重複していた画像のうち代表以外のBlobはどこからも参照されなくなるので、必要なら手動で削除する
+CREATE TABLE image_blobs (
+    hash        CHAR(64)     PRIMARY KEY,
+    blob_key    VARCHAR(255) NOT NULL,
+    mime        VARCHAR(64)  NOT NULL,
+    size        BIGINT       NOT NULL,
+    ref_count   INT          NOT NULL DEFAULT 0,
+    created_at  DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO image_blobs (hash, blob_key, mime, size, ref_count, created_at)
+    SELECT content_hash, MIN(blob_key), MIN(mime), MAX(size), COUNT(*), MIN(created_at)
+    FROM message_images
+    GROUP BY content_hash;
+
+ALTER TABLE message_images
+    DROP COLUMN blob_key,
+    DROP COLUMN mime,
+    DROP COLUMN size,
+    ALTER COLUMN content_hash DROP DEFAULT,
+    ADD FOREIGN KEY (content_hash) REFERENCES image_blobs(hash);
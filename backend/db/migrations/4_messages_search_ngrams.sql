@@ -0,0 +1,5 @@
+-- メッセージの全文検索用のカラムとインデックス
+-- 既存のメッセージは go run ./cmd/reindex-search で埋める
+ALTER TABLE messages
+    ADD COLUMN search_ngrams MEDIUMTEXT NOT NULL DEFAULT '' AFTER replies_to,
+    ADD FULLTEXT INDEX idx_search_ngrams (search_ngrams);
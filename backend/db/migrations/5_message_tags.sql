@@ -0,0 +1,12 @@
+-- メッセージ本文のハッシュタグ
+-- 既存のメッセージのタグは抽出しない
+CREATE TABLE message_tags (
+    message_id  CHAR(36)    NOT NULL,
+    tag         VARCHAR(64) NOT NULL,
+    position    INT         NOT NULL,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (message_id, tag),
+    FOREIGN KEY (message_id) REFERENCES messages(id),
+    INDEX idx_tag_created_at (tag, created_at),
+    INDEX idx_created_at (created_at)
+);
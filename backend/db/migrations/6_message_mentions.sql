@@ -0,0 +1,11 @@
+-- メッセージ本文の @username
+-- 既存のメッセージのメンションは抽出しない
+CREATE TABLE message_mentions (
+    message_id  CHAR(36)    NOT NULL,
+    username    VARCHAR(32) NOT NULL,
+    `offset`    INT         NOT NULL, -- 本文中の位置(文字数)
+    length      INT         NOT NULL,
+    PRIMARY KEY (message_id, `offset`),
+    FOREIGN KEY (message_id) REFERENCES messages(id),
+    INDEX idx_username (username)
+);
@@ -0,0 +1,13 @@
+-- 返信・リアクション・メンションの通知
+CREATE TABLE notifications (
+    id          CHAR(36)    PRIMARY KEY,
+    username    VARCHAR(32) NOT NULL, -- 通知を受け取るユーザー
+    type        VARCHAR(16) NOT NULL, -- reply, reaction, mention
+    actor       VARCHAR(32) NOT NULL,
+    message_id  CHAR(36)    NOT NULL,
+    is_read     BOOLEAN     NOT NULL DEFAULT FALSE,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (message_id) REFERENCES messages(id),
+    INDEX idx_username_created_at (username, created_at),
+    INDEX idx_username_is_read (username, is_read)
+);
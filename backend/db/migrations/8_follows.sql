@@ -0,0 +1,8 @@
+-- ユーザーのフォロー関係
+CREATE TABLE follows (
+    follower    VARCHAR(32) NOT NULL,
+    followee    VARCHAR(32) NOT NULL,
+    created_at  DATETIME    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower, followee),
+    INDEX idx_followee (followee)
+);
@@ -0,0 +1,15 @@
+-- ユーザーのプロフィール
+-- X-Forwarded-User のユーザーが初めてリクエストしたときに作成される
+CREATE TABLE users (
+    username     VARCHAR(32)  PRIMARY KEY,
+    display_name VARCHAR(32)  NOT NULL DEFAULT '',
+    bio          VARCHAR(160) NOT NULL DEFAULT '',
+    created_at   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- 既に投稿・リアクションしたことのあるユーザーを作成しておく
+INSERT IGNORE INTO users (username, created_at)
+    SELECT author, MIN(created_at) FROM messages GROUP BY author;
+INSERT IGNORE INTO users (username, created_at)
+    SELECT username, MIN(created_at) FROM message_reactions GROUP BY username;
@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Bookmark struct {
+	MessageID uuid.UUID
+	Username  string
+	CreatedAt time.Time
+}
+
+// BookmarkCursor はブックマーク一覧のページ位置。ゼロ値は先頭を表す
+type BookmarkCursor struct {
+	CreatedAt time.Time
+	MessageID uuid.UUID
+}
+
+// BookmarkedMessage はブックマークしたメッセージとブックマークした日時
+type BookmarkedMessage struct {
+	Message      Message
+	BookmarkedAt time.Time
+}
@@ -6,4 +6,5 @@ var (
 	ErrConflict       = errors.New("value already exists")
 	ErrNotFound       = errors.New("not found")
 	ErrNotImplemented = errors.New("not implemented")
+	ErrLimitExceeded  = errors.New("limit exceeded")
 )
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+type Follow struct {
+	Follower  string
+	Followee  string
+	CreatedAt time.Time
+}
+
+type FollowCounts struct {
+	Followers int64
+	Following int64
+}
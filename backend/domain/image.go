@@ -9,7 +9,19 @@ import (
 type MessageImage struct {
 	ID        uuid.UUID
 	MessageID uuid.UUID
-	Data      []byte
+	BlobKey   string
+	Hash      string // 内容のSHA-256(hex)
 	Mime      string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// ImageBlob は内容のハッシュで一意に識別される画像の実体で、複数のMessageImageから参照される
+type ImageBlob struct {
+	Hash      string
+	BlobKey   string
+	Mime      string
+	Size      int64
+	RefCount  int64
 	CreatedAt time.Time
 }
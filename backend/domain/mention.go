@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// traQ IDは英数字と _ - からなる32文字以内の文字列
+// @ の直前が英数字などのとき(メールアドレスなど)はメンションとみなさない
+// 33文字以上続くときは先頭の32文字だけが一致するので、ExtractMentions で取り除く
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_\-.])(@([A-Za-z0-9_\-]{1,32}))`)
+
+// Mention は本文中の @username の位置を表す。Offset と Length は文字(rune)単位
+type Mention struct {
+	Username string
+	Offset   int
+	Length   int
+}
+
+// ExtractMentions は本文に含まれるメンションを出現順に返す
+func ExtractMentions(content string) []Mention {
+	mentions := []Mention{}
+	for _, m := range mentionPattern.FindAllStringSubmatchIndex(content, -1) {
+		start, end := m[2], m[3]
+		// traQ ID にならない長さの @ を別のユーザーへのメンションにしない
+		if end < len(content) && isUsernameByte(content[end]) {
+			continue
+		}
+		mentions = append(mentions, Mention{
+			Username: content[m[4]:m[5]],
+			Offset:   utf8.RuneCountInString(content[:start]),
+			Length:   utf8.RuneCountInString(content[start:end]),
+		})
+	}
+	return mentions
+}
+
+func isUsernameByte(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' || b == '_' || b == '-'
+}
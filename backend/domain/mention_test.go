@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractMentions(t *testing.T) {
+	long := strings.Repeat("a", 32)
+	tests := []struct {
+		name    string
+		content string
+		want    []Mention
+	}{
+		{"none", "hello", []Mention{}},
+		{"single", "@alice", []Mention{{"alice", 0, 6}}},
+		{"in sentence", "hi @alice and @bob_2-x!", []Mention{{"alice", 3, 6}, {"bob_2-x", 14, 8}}},
+		{"email is not a mention", "mail a@b.com", []Mention{}},
+		{"dot before at", "x.@alice", []Mention{}},
+		{"double at", "@@x", []Mention{{"x", 1, 2}}},
+		{"at only", "@ @", []Mention{}},
+		{"punctuation ends name", "(@alice), @bob.", []Mention{{"alice", 1, 6}, {"bob", 10, 4}}},
+		{"offset in runes after multibyte text", "こんにちは @alice", []Mention{{"alice", 6, 6}}},
+		{"offset after emoji", "👍@alice", []Mention{{"alice", 1, 6}}},
+		{"multibyte after name", "@aliceさん", []Mention{{"alice", 0, 6}}},
+		{"duplicates are kept", "@a @a", []Mention{{"a", 0, 2}, {"a", 3, 2}}},
+		{"at max length", "@" + long, []Mention{{long, 0, 33}}},
+		{"over max length is not truncated", "@" + long + "b @ok", []Mention{{"ok", 35, 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractMentions(tt.content); !slices.Equal(got, tt.want) {
+				t.Fatalf("ExtractMentions(%q) = %+v, want %+v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
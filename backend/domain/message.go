@@ -7,10 +7,26 @@ import (
 )
 
 type Message struct {
-	ID         uuid.UUID
-	Author     string
-	Content    string
-	ParentID   uuid.UUID
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
-}
\ No newline at end of file
+	ID        uuid.UUID
+	Author    string
+	Content   string
+	ParentID  uuid.UUID
+	QuoteOf   uuid.UUID // 引用したメッセージのID
+	Status    MessageStatus
+	PublishAt time.Time // 予約投稿の公開日時。予約投稿でなければゼロ値
+	TokenID   uuid.UUID // トークンで投稿した場合のトークンID。そうでなければ uuid.Nil
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type MessageStatus string
+
+const (
+	MessageStatusPublished MessageStatus = "published"
+	// MessageStatusScheduled は公開日時を待っている予約投稿
+	MessageStatusScheduled MessageStatus = "scheduled"
+	// MessageStatusCanceled は公開前に取り消された予約投稿
+	MessageStatusCanceled MessageStatus = "canceled"
+	// MessageStatusHidden はモデレーターが非表示にしたメッセージ
+	MessageStatusHidden MessageStatus = "hidden"
+)
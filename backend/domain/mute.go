@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// Mute はミュートしたユーザーのメッセージをタイムラインに表示しないことを表す
+type Mute struct {
+	Muter     string
+	Mutee     string
+	CreatedAt time.Time
+}
+
+// Block はミュートに加えて、ブロックされたユーザーからの返信とリアクションを禁止する
+// ブロックされたユーザーからもブロックしたユーザーのメッセージは見えなくなる
+type Block struct {
+	Blocker   string
+	Blocked   string
+	CreatedAt time.Time
+}
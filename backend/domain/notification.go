@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NotificationType string
+
+const (
+	NotificationTypeReply    NotificationType = "reply"
+	NotificationTypeReaction NotificationType = "reaction"
+	NotificationTypeMention  NotificationType = "mention"
+)
+
+type Notification struct {
+	ID        uuid.UUID
+	Username  string // 通知を受け取るユーザー
+	Type      NotificationType
+	Actor     string    // 返信・リアクション・メンションをしたユーザー
+	MessageID uuid.UUID // reply, mention は新しいメッセージ、reaction はリアクションされたメッセージ
+	Read      bool
+	CreatedAt time.Time
+}
@@ -0,0 +1,4 @@
+package domain
+
+// MaxPinnedMessages はプロフィールに固定できるメッセージの最大数
+const MaxPinnedMessages = 3
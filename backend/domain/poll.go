@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MinPollOptions      = 2
+	MaxPollOptions      = 4
+	MaxPollOptionLength = 64
+	// MaxPollDuration は投票を受け付ける期間の上限
+	MaxPollDuration = 7 * 24 * time.Hour
+)
+
+type Poll struct {
+	MessageID uuid.UUID
+	Multiple  bool // 複数選択できるかどうか
+	ExpiresAt time.Time
+	Options   []PollOption
+	// Voters は投票したユーザーの数。複数選択の場合は各選択肢の票数の合計と一致しない
+	Voters int64
+}
+
+// NewPoll はメッセージと一緒に作る投票の内容
+type NewPoll struct {
+	Options   []string
+	Multiple  bool
+	ExpiresAt time.Time
+}
+
+type PollOption struct {
+	Index int
+	Label string
+	Votes int64
+}
+
+func (p *Poll) Expired(now time.Time) bool {
+	return !now.Before(p.ExpiresAt)
+}
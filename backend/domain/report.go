@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const MaxReportReasonLength = 200
+
+// MaxSuspension は投稿停止にできる期間の上限
+const MaxSuspension = 365 * 24 * time.Hour
+
+type Report struct {
+	ID        uuid.UUID
+	MessageID uuid.UUID
+	Reporter  string
+	Reason    string
+	CreatedAt time.Time
+}
+
+// ReportedMessage は未対応の通報があるメッセージ
+type ReportedMessage struct {
+	Message        Message
+	ReportCount    int64
+	LastReportedAt time.Time
+}
+
+type ModerationAction string
+
+const (
+	// ModerationActionDismiss は通報を問題なしとして閉じる
+	ModerationActionDismiss ModerationAction = "dismiss"
+	// ModerationActionHide はメッセージを非表示にする
+	ModerationActionHide ModerationAction = "hide"
+	// ModerationActionSuspend はメッセージの作者を一定期間投稿できないようにする
+	ModerationActionSuspend ModerationAction = "suspend"
+	ModerationActionUnhide  ModerationAction = "unhide"
+	// ModerationActionRemoveReaction と ModerationActionRevokeAchievement は通報によらない操作
+	ModerationActionRemoveReaction    ModerationAction = "remove_reaction"
+	ModerationActionRevokeAchievement ModerationAction = "revoke_achievement"
+)
+
+// ModerationLog はモデレーターの操作の記録
+type ModerationLog struct {
+	ID         uuid.UUID
+	Moderator  string
+	Action     ModerationAction
+	MessageID  uuid.UUID // メッセージに対する操作でなければ uuid.Nil
+	TargetUser string
+	// SuspendedUntil は投稿停止の場合のみ
+	SuspendedUntil time.Time
+	Note           string
+	CreatedAt      time.Time
+}
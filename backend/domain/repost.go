@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Repost struct {
+	MessageID uuid.UUID
+	Username  string
+	CreatedAt time.Time
+}
+
+// TimelineEntry はタイムラインの1件。リポストの場合は RepostedBy が空でない
+type TimelineEntry struct {
+	Message    Message
+	RepostedBy string
+	RepostedAt time.Time
+}
@@ -0,0 +1,33 @@
+package domain
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleUser:      0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// AtLeast は r が required 以上の権限を持つかを返す。admin は moderator の権限も持つ
+func (r Role) AtLeast(required Role) bool {
+	return roleRanks[r] >= roleRanks[required]
+}
+
+// HigherRole は a と b のうち権限が強い方を返す
+func HigherRole(a, b Role) Role {
+	if a.AtLeast(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+type SearchOrder string
+
+const (
+	SearchOrderRelevance SearchOrder = "relevance"
+	SearchOrderRecent    SearchOrder = "recent"
+)
+
+type MessageSearchQuery struct {
+	Terms    []string // 含まれるべき語句。空白を含むものはフレーズとして扱う
+	Excludes []string // 含まれてはいけない語句
+	Authors  []string
+	Since    time.Time // ゼロ値なら制限しない
+	Until    time.Time // ゼロ値なら制限しない
+	Order    SearchOrder
+	Viewer   string // このユーザーがミュート・ブロックしているユーザーと、このユーザーをブロックしているユーザーのメッセージは除く
+	Limit    int64
+	Offset   int64
+}
@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const MaxTagLength = 64
+
+// # の直前が文字・数字・_・/ のとき(URLのフラグメントや a#b など)はハッシュタグとみなさない
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&/])[#＃]([\p{L}\p{N}_]+)`)
+
+type TagCount struct {
+	Tag   string
+	Count int64
+}
+
+// NormalizeTag は全角・半角や大文字・小文字の違いをなくしたタグ名を返す
+func NormalizeTag(tag string) string {
+	return strings.ToLower(norm.NFKC.String(tag))
+}
+
+// ExtractHashtags は本文に含まれるハッシュタグを重複なく出現順に返す
+func ExtractHashtags(content string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, m := range hashtagPattern.FindAllStringSubmatch(content, -1) {
+		tag := NormalizeTag(m[1])
+		if seen[tag] || utf8.RuneCountInString(tag) > MaxTagLength {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
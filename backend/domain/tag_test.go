@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"none", "hello world", []string{}},
+		{"single", "#go", []string{"go"}},
+		{"in sentence", "I like #Go and #rust.", []string{"go", "rust"}},
+		{"punctuation ends tag", "#go!#rust,(#zig)", []string{"go", "rust", "zig"}},
+		{"after newline", "line\n#tag", []string{"tag"}},
+		{"full-width hash", "＃日本語 のタグ", []string{"日本語"}},
+		{"full-width letters are normalized", "#ＧＯ", []string{"go"}},
+		{"underscore and digits", "#go_1_22", []string{"go_1_22"}},
+		{"trailing hash", "hello #", []string{}},
+		{"hash only", "# #", []string{}},
+		{"double hash", "##go", []string{"go"}},
+		{"hash inside word", "a#b c#", []string{}},
+		{"adjacent tags", "#a#b", []string{"a"}},
+		{"url fragment", "https://example.com/#section", []string{}},
+		{"html entity", "&#39;", []string{}},
+		{"duplicates keep first", "#b #a #B #a", []string{"b", "a"}},
+		{"at max length", "#" + strings.Repeat("a", MaxTagLength), []string{strings.Repeat("a", MaxTagLength)}},
+		{"over max length is skipped", "#" + strings.Repeat("a", MaxTagLength+1) + " #ok", []string{"ok"}},
+		{"max length in runes", "#" + strings.Repeat("あ", MaxTagLength), []string{strings.Repeat("あ", MaxTagLength)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractHashtags(tt.content); !slices.Equal(got, tt.want) {
+				t.Fatalf("ExtractHashtags(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"Go", "go"},
+		{"ＧＯ", "go"},
+		{"ｶﾀｶﾅ", "カタカナ"},
+		{"日本語", "日本語"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTag(tt.tag); got != tt.want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
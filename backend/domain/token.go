@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TokenScope string
+
+const (
+	// TokenScopeRead は GET で取得する API だけを使える
+	TokenScopeRead TokenScope = "read"
+	// TokenScopePost はメッセージを投稿できる
+	TokenScopePost TokenScope = "post"
+	// TokenScopeReact はリアクションと投票ができる
+	TokenScopeReact TokenScope = "react"
+)
+
+var TokenScopes = []TokenScope{TokenScopeRead, TokenScopePost, TokenScopeReact}
+
+const MaxTokenNameLength = 64
+
+// PersonalAccessToken はボットやスクリプト用のトークン。トークン自体はハッシュだけを保存する
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	Username   string
+	Name       string
+	Scopes     []TokenScope
+	CreatedAt  time.Time
+	LastUsedAt time.Time // 使われたことがなければゼロ値
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"regexp"
+	"time"
+)
+
+const (
+	MaxUsernameLength    = 32
+	MaxDisplayNameLength = 32
+	MaxBioLength         = 160
+)
+
+// traQ IDは英数字と _ - からなる32文字以内の文字列
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_\-]{1,32}$`)
+
+// IsValidUsername は s が traQ ID として正しいかどうか
+func IsValidUsername(s string) bool {
+	return usernamePattern.MatchString(s)
+}
+
+type User struct {
+	Username    string
+	DisplayName string
+	Bio         string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type UserStats struct {
+	PostCount         int64
+	ReplyCount        int64
+	ReactionsReceived int64
+	ReactionsGiven    int64
+	AchievementsCount int64
+	FirstPostAt       *time.Time // 投稿がなければnil
+	LastPostAt        *time.Time
+}
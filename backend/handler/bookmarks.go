@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"cmp"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+type bookmarkEntry struct {
+	message
+	BookmarkedAt time.Time `json:"bookmarkedAt"`
+}
+
+type bookmarkPage struct {
+	Bookmarks []bookmarkEntry `json:"bookmarks"`
+	// 次のページがなければ null
+	NextCursor *string `json:"nextCursor"`
+}
+
+// encodeBookmarkCursor はブックマークの日時とメッセージIDを不透明な文字列にする
+func encodeBookmarkCursor(c domain.BookmarkCursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "_" + c.MessageID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeBookmarkCursor(s string) (domain.BookmarkCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return domain.BookmarkCursor{}, err
+	}
+	createdAt, messageID, ok := strings.Cut(string(raw), "_")
+	if !ok {
+		return domain.BookmarkCursor{}, errors.New("malformed cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return domain.BookmarkCursor{}, err
+	}
+	id, err := uuid.Parse(messageID)
+	if err != nil {
+		return domain.BookmarkCursor{}, err
+	}
+	return domain.BookmarkCursor{CreatedAt: t, MessageID: id}, nil
+}
+
+func (h *handler) BookmarkAdder(c echo.Context) error {
+	ID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
+	}
+	msg, err := h.repo.GetMessageByID(ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "id not found")
+		}
+		c.Logger().Error("Failed to retrieve message:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve message")
+	}
+	if msg.Status != domain.MessageStatusPublished {
+		return echo.NewHTTPError(http.StatusNotFound, "id not found")
+	}
+	username := c.Get(m.UsernameKey).(string)
+	if err := h.repo.InsertBookmark(ID, username); err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			return echo.NewHTTPError(http.StatusConflict, "already bookmarked")
+		}
+		c.Logger().Error("Failed to insert bookmark:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to insert bookmark")
+	}
+	return c.JSON(http.StatusCreated, map[string]bool{"bookmarked": true})
+}
+
+func (h *handler) BookmarkDeleter(c echo.Context) error {
+	ID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
+	}
+	username := c.Get(m.UsernameKey).(string)
+	if err := h.repo.DeleteBookmark(ID, username); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "bookmark not found")
+		}
+		c.Logger().Error("Failed to delete bookmark:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete bookmark")
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"bookmarked": false})
+}
+
+func (h *handler) GetMyBookmarksHandler(ctx echo.Context) error {
+	username := ctx.Get(m.UsernameKey).(string)
+	limit, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("limit"), "20"), 10, 64)
+	if err != nil || limit <= 0 || limit > 100 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit parameter")
+	}
+	var cursor domain.BookmarkCursor
+	if s := ctx.QueryParam("cursor"); s != "" {
+		cursor, err = decodeBookmarkCursor(s)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid cursor parameter")
+		}
+	}
+
+	// 次のページがあるかを知るために1件多く取得する
+	bookmarks, err := h.repo.GetBookmarkedMessages(username, cursor, limit+1)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve bookmarks:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve bookmarks")
+	}
+	page := bookmarkPage{Bookmarks: make([]bookmarkEntry, 0, len(bookmarks))}
+	if int64(len(bookmarks)) > limit {
+		bookmarks = bookmarks[:limit]
+		last := bookmarks[len(bookmarks)-1]
+		next := encodeBookmarkCursor(domain.BookmarkCursor{CreatedAt: last.BookmarkedAt, MessageID: last.Message.ID})
+		page.NextCursor = &next
+	}
+	for _, b := range bookmarks {
+		msg, err := h.buildMessage(ctx, b.Message, true)
+		if err != nil {
+			return err
+		}
+		page.Bookmarks = append(page.Bookmarks, bookmarkEntry{message: msg, BookmarkedAt: b.BookmarkedAt})
+	}
+	return ctx.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+type followUser struct {
+	TraqID     string    `json:"traqId"`
+	FollowedAt time.Time `json:"followedAt"`
+}
+
+// timelineEntry はタイムラインの1件。リポストの場合は誰がいつリポストしたかを含む
+type timelineEntry struct {
+	message
+	RepostedBy string     `json:"repostedBy,omitempty"`
+	RepostedAt *time.Time `json:"repostedAt,omitempty"`
+}
+
+type followCounts struct {
+	Followers   int64 `json:"followers"`
+	Following   int64 `json:"following"`
+	IsFollowing bool  `json:"isFollowing"` // 自分がこのユーザーをフォローしているか
+}
+
+func (h *handler) FollowUserHandler(ctx echo.Context) error {
+	username := ctx.Get(m.UsernameKey).(string)
+	target := ctx.Param("name")
+	if target == "" {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	if target == username {
+		return echo.NewHTTPError(http.StatusBadRequest, "cannot follow yourself")
+	}
+	if err := h.repo.InsertFollow(username, target); err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			return echo.NewHTTPError(http.StatusConflict, "already following")
+		}
+		ctx.Logger().Error("Failed to follow user:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to follow user")
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (h *handler) UnfollowUserHandler(ctx echo.Context) error {
+	username := ctx.Get(m.UsernameKey).(string)
+	target := ctx.Param("name")
+	if target == "" {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	if err := h.repo.DeleteFollow(username, target); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "not following")
+		}
+		ctx.Logger().Error("Failed to unfollow user:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to unfollow user")
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (h *handler) GetFollowersHandler(ctx echo.Context) error {
+	return h.getFollowList(ctx, h.repo.GetFollowers, func(f domain.Follow) string { return f.Follower })
+}
+
+func (h *handler) GetFollowingHandler(ctx echo.Context) error {
+	return h.getFollowList(ctx, h.repo.GetFollowing, func(f domain.Follow) string { return f.Followee })
+}
+
+func (h *handler) getFollowList(ctx echo.Context, get func(username string, limit, offset int64) ([]domain.Follow, error), other func(domain.Follow) string) error {
+	username := ctx.Param("name")
+	if username == "" {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	limit, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("limit"), "50"), 10, 64)
+	if err != nil || limit <= 0 || limit > 100 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit parameter")
+	}
+	offset, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("offset"), "0"), 10, 64)
+	if err != nil || offset < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offset parameter")
+	}
+
+	follows, err := get(username, limit, offset)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve follows:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve follows")
+	}
+	result := make([]followUser, len(follows))
+	for i, f := range follows {
+		result[i] = followUser{
+			TraqID:     other(f),
+			FollowedAt: f.CreatedAt,
+		}
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+func (h *handler) GetFollowCountsHandler(ctx echo.Context) error {
+	me := ctx.Get(m.UsernameKey).(string)
+	username := ctx.Param("name")
+	if username == "" {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	counts, err := h.repo.GetFollowCounts(username)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve follow counts:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve follow counts")
+	}
+	isFollowing, err := h.repo.IsFollowing(me, username)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve follow state:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve follow counts")
+	}
+	return ctx.JSON(http.StatusOK, followCounts{
+		Followers:   counts.Followers,
+		Following:   counts.Following,
+		IsFollowing: isFollowing,
+	})
+}
+
+// GetMyTimelineHandler は自分とフォローしているユーザーのメッセージを返す
+func (h *handler) GetMyTimelineHandler(ctx echo.Context) error {
+	username := ctx.Get(m.UsernameKey).(string)
+	limit, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("limit"), "20"), 10, 64)
+	if err != nil || limit <= 0 || limit > 100 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit parameter")
+	}
+	offset, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("offset"), "0"), 10, 64)
+	if err != nil || offset < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offset parameter")
+	}
+	includeReplies := ctx.QueryParam("includeReplies") == "true"
+
+	entries, err := h.repo.GetTimeline(username, limit, offset, includeReplies)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve timeline:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	result := make([]timelineEntry, len(entries))
+	for i, entry := range entries {
+		msg, err := h.buildMessage(ctx, entry.Message, true)
+		if err != nil {
+			return err
+		}
+		result[i] = timelineEntry{message: msg}
+		if entry.RepostedBy != "" {
+			result[i].RepostedBy = entry.RepostedBy
+			result[i].RepostedAt = &entry.RepostedAt
+		}
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
@@ -1,60 +1,185 @@
 package handler
 
 import (
-	"cmp"
-	"os"
+	"strings"
 
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/traP-jp/h25s_09/contentrule"
+	"github.com/traP-jp/h25s_09/domain"
 	m "github.com/traP-jp/h25s_09/handler/middleware"
 	"github.com/traP-jp/h25s_09/repository"
+	"github.com/traP-jp/h25s_09/storage"
 )
 
 type handler struct {
-	repo repository.Repository
-	ss   sessions.Store
+	repo  repository.Repository
+	blobs storage.BlobStore
+	ss    sessions.Store
+	// schedulerWake は予約投稿が作られたときにスケジューラーを起こす
+	schedulerWake chan struct{}
+	// envRoles は環境変数で決めたユーザーの権限
+	envRoles map[string]domain.Role
+	// contentRules はメッセージ本文のルール
+	contentRules *contentrule.Rules
+	// registeredUsers は UserRegisterer が users テーブルにあると確認したユーザー
+	registeredUsers *userCache
 }
 
 func Start() {
 	e := echo.New()
+	// レート制限などで使うクライアントの IP アドレスは信頼するプロキシ (TRUSTED_PROXY_CIDRS) を通ったときだけ X-Forwarded-For から取る
+	proxies, err := m.NewProxyVerifierFromEnv()
+	if err != nil {
+		e.Logger.Fatal("Failed to configure trusted proxies:", err)
+	}
+	e.IPExtractor = proxies.IPExtractor()
 	e.Use(middleware.Logger(), middleware.Recover())
-	e.Use(m.UsernameProvider)
 
-	ss := sessions.NewCookieStore([]byte(cmp.Or(os.Getenv("SESSION_SECRET"), "secret")))
+	ss := sessions.NewCookieStore(m.SessionSecretFromEnv())
 	e.Use(session.Middleware(ss))
+	e.Use(m.CSRFProtection())
 
 	db, err := repository.NewDB()
 	if err != nil {
 		e.Logger.Fatal("Failed to connect to the database:", err)
 	}
+	blobs, err := storage.NewBlobStore()
+	if err != nil {
+		e.Logger.Fatal("Failed to initialize the blob store:", err)
+	}
+	contentRules, err := contentrule.NewFromEnv()
+	if err != nil {
+		e.Logger.Fatal("Failed to load content rules:", err)
+	}
 	h := &handler{
-		repo: repository.NewRepository(db),
-		ss:   ss,
+		repo:            repository.NewRepository(db),
+		blobs:           blobs,
+		ss:              ss,
+		schedulerWake:   make(chan struct{}, 1),
+		envRoles:        loadEnvRoles(),
+		contentRules:    contentRules,
+		registeredUsers: newUserCache(registeredUsersSize, registeredUsersTTL),
+	}
+	auth, err := m.NewAuthenticatorsFromEnv(h)
+	if err != nil {
+		e.Logger.Fatal("Failed to configure authentication:", err)
+	}
+	e.Use(m.UsernameProvider(m.UsernameProviderConfig{
+		// ログインの画面はログインしていなくても使える
+		Skipper: func(c echo.Context) bool {
+			return strings.HasPrefix(c.Path(), "/api/auth/") || strings.HasPrefix(c.Path(), "/api/dev/")
+		},
+		Authenticators: auth.List,
+	}))
+	e.Use(h.UserRegisterer)
+	e.Use(h.TokenScopeChecker)
+	go h.runScheduler(e.Logger)
+
+	limits, err := m.NewRateLimitsFromEnv()
+	if err != nil {
+		e.Logger.Fatal("Failed to configure rate limits:", err)
+	}
+	rateLimitStore := m.NewMemoryRateLimitStore()
+	rateLimit := func(group string) echo.MiddlewareFunc {
+		return m.RateLimiter(m.RateLimiterConfig{
+			// ヘルスチェックは制限しない
+			Skipper: func(c echo.Context) bool { return c.Path() == "/api/health" },
+			Store:   rateLimitStore,
+			Group:   group,
+			Limit:   limits[group],
+		})
 	}
+	react := rateLimit(m.RateLimitReact)
 
-	g := e.Group("/api")
+	g := e.Group("/api", rateLimit(m.RateLimitDefault))
 	{
 		g.GET("/health", h.GetHealthHandler)
+		g.GET("/csrf-token", h.GetCSRFTokenHandler)
+		if auth.OIDC != nil {
+			g.GET("/auth/login", auth.OIDC.LoginHandler)
+			g.GET("/auth/callback", auth.OIDC.CallbackHandler)
+			g.POST("/auth/logout", auth.OIDC.LogoutHandler)
+		}
+		if auth.Development != nil {
+			g.GET("/dev/login", auth.Development.DevLoginPageHandler)
+			g.POST("/dev/login", auth.Development.DevLoginHandler)
+			g.POST("/dev/logout", auth.Development.DevLogoutHandler)
+		}
 		g.GET("/images/:id", h.GetMessageImageHandler)
+		tags := g.Group("/tags")
+		{
+			tags.GET("/trending", h.GetTrendingTagsHandler)
+			tags.GET("/:tag/messages", h.GetTagMessagesHandler)
+		}
 		u := g.Group("/users/:name")
 		{
+			u.GET("", h.GetUserProfileHandler)
 			u.GET("/achievements", h.GetUserAchievementsHandler)
+			u.GET("/pinned", h.GetUserPinnedMessagesHandler)
+			u.POST("/follow", h.FollowUserHandler, react)
+			u.DELETE("/follow", h.UnfollowUserHandler, react)
+			u.GET("/followers", h.GetFollowersHandler)
+			u.GET("/following", h.GetFollowingHandler)
+			u.GET("/follow-counts", h.GetFollowCountsHandler)
 		}
 		me := g.Group("/me")
 		{
 			me.GET("", h.GetMeHandler)
+			me.PATCH("", h.PatchMeHandler)
 			me.GET("/achievements", h.GetMyAchievementsHandler)
 			me.POST("/achievements", h.PostAchievementsHandler)
+			me.GET("/mentions", h.GetMyMentionsHandler)
+			me.GET("/timeline", h.GetMyTimelineHandler)
+			me.GET("/bookmarks", h.GetMyBookmarksHandler)
+			me.GET("/scheduled", h.GetMyScheduledMessagesHandler)
+			me.GET("/tokens", h.GetMyTokensHandler)
+			me.POST("/tokens", h.CreateTokenHandler)
+			me.DELETE("/tokens/:id", h.RevokeTokenHandler)
+			me.DELETE("/scheduled/:id", h.CancelScheduledMessageHandler)
+			me.GET("/mutes", h.GetMyMutesHandler)
+			me.POST("/mutes", h.MuteUserHandler)
+			me.DELETE("/mutes/:name", h.UnmuteUserHandler)
+			me.GET("/blocks", h.GetMyBlocksHandler)
+			me.POST("/blocks", h.BlockUserHandler)
+			me.DELETE("/blocks/:name", h.UnblockUserHandler)
+			me.GET("/notifications", h.GetMyNotificationsHandler)
+			me.GET("/notifications/unread-count", h.GetMyUnreadNotificationCountHandler)
+			me.POST("/notifications/read", h.ReadAllNotificationsHandler)
+			me.POST("/notifications/:id/read", h.ReadNotificationHandler)
 		}
 		msg := g.Group("/messages")
 		{
 			msg.GET("", h.GetMessagesHandler)
-			msg.POST("", h.PostMessageHandler)      
-		  msg.GET("/:id", h.GetMessageHandler)
-			msg.POST("/:id/reactions", h.ReactionsAdder)
-			msg.DELETE("/:id/reactions", h.ReactionsDeleter)
+			msg.POST("", h.PostMessageHandler, rateLimit(m.RateLimitPost))
+			msg.GET("/search", h.SearchMessagesHandler)
+			msg.GET("/:id", h.GetMessageHandler)
+			msg.POST("/:id/reactions", h.ReactionsAdder, react)
+			msg.DELETE("/:id/reactions", h.ReactionsDeleter, react)
+			msg.POST("/:id/repost", h.RepostAdder, react)
+			msg.DELETE("/:id/repost", h.RepostDeleter, react)
+			msg.POST("/:id/bookmark", h.BookmarkAdder, react)
+			msg.DELETE("/:id/bookmark", h.BookmarkDeleter, react)
+			msg.POST("/:id/pin", h.PinMessageHandler, react)
+			msg.DELETE("/:id/pin", h.UnpinMessageHandler, react)
+			msg.POST("/:id/poll/votes", h.VotePollHandler, react)
+			msg.POST("/:id/reports", h.ReportMessageHandler, react)
+		}
+		mod := g.Group("/mod", h.RequireRole(domain.RoleModerator))
+		{
+			mod.POST("/messages/:id/hide", h.HideMessageHandler)
+			mod.POST("/messages/:id/unhide", h.UnhideMessageHandler)
+			mod.DELETE("/messages/:id/reactions/:username", h.RemoveReactionHandler)
+			mod.DELETE("/users/:name/achievements/:achievement", h.RevokeAchievementHandler)
+			mod.GET("/reports", h.GetReportQueueHandler)
+			mod.POST("/reports/:id/actions", h.ResolveReportsHandler)
+			mod.GET("/logs", h.GetModerationLogsHandler)
+		}
+		admin := g.Group("/admin", h.RequireRole(domain.RoleAdmin))
+		{
+			admin.PUT("/users/:name/role", h.SetUserRoleHandler)
 		}
 	}
 
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+	"github.com/traP-jp/h25s_09/repository"
+	"github.com/traP-jp/h25s_09/storage"
+)
+
+// fakeRepo はテストで使うメソッドだけを上書きする。それ以外を呼ぶと nil の埋め込みで panic する
+type fakeRepo struct {
+	repository.Repository
+}
+
+func newTestHandler(t *testing.T, repo repository.Repository) *handler {
+	t.Helper()
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &handler{
+		repo:            repo,
+		blobs:           blobs,
+		schedulerWake:   make(chan struct{}, 1),
+		registeredUsers: newUserCache(registeredUsersSize, registeredUsersTTL),
+	}
+}
+
+// newTestContext は username としてログインしたリクエストの echo.Context を作る
+// params にはパスパラメーターの名前と値を交互に渡す
+func newTestContext(method, target, username string, params ...string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(method, target, nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	var names, values []string
+	for i := 0; i+1 < len(params); i += 2 {
+		names = append(names, params[i])
+		values = append(values, params[i+1])
+	}
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+	if username != "" {
+		c.Set(m.UsernameKey, username)
+	}
+	return c, rec
+}
+
+// messageRepo は GetMessageByID で messages を返し、メッセージに付随するものは何もないものとして扱う
+// 書き込みが呼ばれたら written に記録する。blocks には {ブロックした人, された人} を入れる
+type messageRepo struct {
+	fakeRepo
+	messages map[uuid.UUID]*domain.Message
+	blocks   map[[2]string]bool
+	written  bool
+}
+
+func (r *messageRepo) GetMessageByID(id uuid.UUID) (*domain.Message, error) {
+	msg, ok := r.messages[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return msg, nil
+}
+
+func (r *messageRepo) GetMessageImageIDByMessageID(messageID uuid.UUID) (uuid.UUID, error) {
+	return uuid.Nil, domain.ErrNotFound
+}
+
+func (r *messageRepo) GetRepliesByMessageID(messageID uuid.UUID) ([]*domain.Message, error) {
+	return nil, nil
+}
+
+func (r *messageRepo) GetReactionsToMessage(messageID uuid.UUID) ([]*domain.MessageReaction, error) {
+	return nil, nil
+}
+
+func (r *messageRepo) GetRepostsOfMessage(messageID uuid.UUID) ([]*domain.Repost, error) {
+	return nil, nil
+}
+
+func (r *messageRepo) IsBookmarked(messageID uuid.UUID, username string) (bool, error) {
+	return false, nil
+}
+
+func (r *messageRepo) IsPinned(messageID uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func (r *messageRepo) GetTagsByMessageID(messageID uuid.UUID) ([]string, error) {
+	return nil, nil
+}
+
+func (r *messageRepo) GetMentionsByMessageID(messageID uuid.UUID) ([]domain.Mention, error) {
+	return nil, nil
+}
+
+func (r *messageRepo) GetPoll(messageID uuid.UUID) (*domain.Poll, error) {
+	return nil, domain.ErrNotFound
+}
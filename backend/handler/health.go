@@ -4,8 +4,16 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
 )
 
 func (h *handler) GetHealthHandler(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, map[string]string{"status": "ok"})
 }
+
+// GetCSRFTokenHandler は GET 以外のリクエストで X-CSRF-Token ヘッダーに入れるトークンを返す
+// Bearer トークンで認証するリクエストでは CSRF を調べないので空になる
+func (h *handler) GetCSRFTokenHandler(ctx echo.Context) error {
+	token, _ := ctx.Get(m.CSRFContextKey).(string)
+	return ctx.JSON(http.StatusOK, map[string]string{"token": token})
+}
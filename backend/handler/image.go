@@ -2,18 +2,22 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"errors"
-	"image"
-	"image/jpeg"
+	"io"
 	"math/rand/v2"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/traP-jp/h25s_09/domain"
+	"github.com/traP-jp/h25s_09/imagefx"
 	"github.com/traP-jp/h25s_09/utils"
 )
 
+// 画像は作成後に変更されないので、バグを起こさないレスポンスは長期間キャッシュさせる
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
 func (h *handler) GetMessageImageHandler(ctx echo.Context) error {
 	imageID, err := uuid.Parse(ctx.Param("id"))
 	if err != nil {
@@ -28,30 +32,115 @@ func (h *handler) GetMessageImageHandler(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve image")
 	}
 
+	blob, err := h.blobs.Get(ctx.Request().Context(), imageObj.BlobKey)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			ctx.Logger().Error("Blob not found for image:", imageID)
+			return echo.NewHTTPError(http.StatusNotFound, "image not found")
+		}
+		ctx.Logger().Error("Failed to retrieve image blob:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve image")
+	}
+	defer blob.Close()
+
 	if utils.DetermineDispatchBug(ctx, h.repo, 3) {
-		if rand.Float64() < 0.5 && len(imageObj.Data) > 0 {
-			imageObj.Data = append(imageObj.Data[:len(imageObj.Data)/2], make([]byte, len(imageObj.Data)/2)...)
+		if rand.Float64() < 0.5 && imageObj.Size > 0 {
+			return h.serveImageWithEffect(ctx, imageObj, blob, imagefx.HalfLoaded())
 		} else {
+			// バグを起こしたレスポンスはキャッシュさせない
+			ctx.Response().Header().Set(echo.HeaderCacheControl, "no-store")
 			return echo.NewHTTPError(http.StatusNotFound)
 		}
 	} else {
 		if utils.DetermineDispatchBug(ctx, h.repo, 8) {
-			// []byte → image.Image に変換
-			imgDecoded, _, err := image.Decode(bytes.NewReader(imageObj.Data))
-			if err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "failed to decode image")
-			}
-			//image.Image→[]byte
-			var buf bytes.Buffer
-			err = jpeg.Encode(&buf, imgDecoded, &jpeg.Options{Quality: 5})
-			if err != nil {
-				return echo.NewHTTPError(http.StatusInternalServerError, "failed to encode resized image")
-			}
-			// Dataを書き換え
-			imageObj.Data = buf.Bytes()
-			imageObj.Mime = "image/jpeg" // MIMEも変更
+			return h.serveImageWithEffect(ctx, imageObj, blob, imagefx.LowQuality(5))
+		}
+	}
+
+	return h.serveImage(ctx, imageObj, blob)
+}
+
+// serveImage はETag, Last-Modified, Rangeに対応して画像を返す
+func (h *handler) serveImage(ctx echo.Context, img *domain.MessageImage, blob io.Reader) error {
+	content, ok := blob.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(blob)
+		if err != nil {
+			ctx.Logger().Error("Failed to read image blob:", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve image")
 		}
+		content = bytes.NewReader(data)
+	}
+
+	header := ctx.Response().Header()
+	header.Set(echo.HeaderContentType, img.Mime)
+	header.Set(echo.HeaderCacheControl, immutableCacheControl)
+	if img.Hash != "" {
+		header.Set("ETag", `"`+img.Hash+`"`)
 	}
+	// If-None-Match / If-Modified-Since による304とRangeによる206はServeContentが処理する
+	http.ServeContent(ctx.Response(), ctx.Request(), "", img.CreatedAt, content)
+	return nil
+}
+
+// serveImageWithEffect は画像を加工して返す。加工できない形式の画像はそのまま返す
+// 加工した画像は元の画像とは別物なので、キャッシュさせず、元の画像の ETag も付けない
+func (h *handler) serveImageWithEffect(ctx echo.Context, img *domain.MessageImage, blob io.Reader, t imagefx.Transform) error {
+	header := ctx.Response().Header()
+	header.Set(echo.HeaderCacheControl, "no-store")
+	header.Del("ETag")
 
-	return ctx.Blob(http.StatusOK, imageObj.Mime, imageObj.Data)
+	data, err := io.ReadAll(blob)
+	if err != nil {
+		ctx.Logger().Error("Failed to read image blob:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve image")
+	}
+	decoded, err := imagefx.Decode(bytes.NewReader(data))
+	if err != nil {
+		ctx.Logger().Warn("Failed to decode image:", img.ID, err)
+		return ctx.Blob(http.StatusOK, img.Mime, data)
+	}
+	decoded.Apply(t)
+	var buf bytes.Buffer
+	mime, err := decoded.Encode(&buf)
+	if err != nil {
+		ctx.Logger().Error("Failed to encode image:", img.ID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to encode image")
+	}
+	return ctx.Blob(http.StatusOK, mime, buf.Bytes())
+}
+
+// deleteBlob はどこからも参照されなくなったBlobを削除する
+// 削除できなくても画像は表示されなくなっているので、ログに出すだけにする
+func (h *handler) deleteBlob(ctx context.Context, logger echo.Logger, key string) {
+	if key == "" {
+		return
+	}
+	if err := h.blobs.Delete(ctx, key); err != nil {
+		logger.Error("Failed to delete orphan blob:", key, err)
+	}
+}
+
+// releaseImageBlob は投稿に失敗したときに AcquireImageBlob で増やした参照を戻し、参照がなくなったBlobを消す
+func (h *handler) releaseImageBlob(c echo.Context, hash string) {
+	ctx, logger := c.Request().Context(), c.Logger()
+	err := h.repo.ReleaseImageBlob(hash, func(key string) { h.deleteBlob(ctx, logger, key) })
+	if err != nil {
+		logger.Error("Failed to release image blob:", hash, err)
+	}
+}
+
+// releaseMessageImage はメッセージに添付された画像を削除し、参照がなくなったBlobも消す
+func (h *handler) releaseMessageImage(ctx context.Context, logger echo.Logger, messageID uuid.UUID) {
+	imageID, err := h.repo.GetMessageImageIDByMessageID(messageID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			logger.Error("Failed to retrieve image of message:", messageID, err)
+		}
+		return
+	}
+	err = h.repo.DeleteMessageImage(imageID, func(key string) { h.deleteBlob(ctx, logger, key) })
+	if err != nil {
+		logger.Error("Failed to delete message image:", imageID, err)
+	}
 }
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	"github.com/traP-jp/h25s_09/imagefx"
+)
+
+// storeTestImage は 8x8 の PNG を LocalStore に保存し、その MessageImage を返す
+func storeTestImage(t *testing.T, h *handler) (*domain.MessageImage, []byte) {
+	t.Helper()
+	src := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	const hash = "5f2b51ca2fdc5baa31ec02e002f69aec"
+	if err := h.blobs.Put(context.Background(), hash, bytes.NewReader(data), int64(len(data)), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	return &domain.MessageImage{
+		ID:        uuid.New(),
+		BlobKey:   hash,
+		Hash:      hash,
+		Mime:      "image/png",
+		Size:      int64(len(data)),
+		CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}, data
+}
+
+func TestServeImage(t *testing.T) {
+	h := newTestHandler(t, &fakeRepo{})
+	img, data := storeTestImage(t, h)
+	etag := `"` + img.Hash + `"`
+	size := strconv.Itoa(len(data))
+
+	tests := []struct {
+		name      string
+		header    map[string]string
+		seekable  bool
+		want      int
+		wantBody  []byte
+		wantRange string
+	}{
+		{"full", nil, true, http.StatusOK, data, ""},
+		{"full from a stream", nil, false, http.StatusOK, data, ""},
+		{"matching ETag", map[string]string{"If-None-Match": etag}, true, http.StatusNotModified, nil, ""},
+		{"other ETag", map[string]string{"If-None-Match": `"other"`}, true, http.StatusOK, data, ""},
+		{"not modified since", map[string]string{"If-Modified-Since": img.CreatedAt.Add(time.Hour).Format(http.TimeFormat)}, true, http.StatusNotModified, nil, ""},
+		{"modified since", map[string]string{"If-Modified-Since": img.CreatedAt.Add(-time.Hour).Format(http.TimeFormat)}, true, http.StatusOK, data, ""},
+		{"range", map[string]string{"Range": "bytes=0-9"}, true, http.StatusPartialContent, data[:10], "bytes 0-9/" + size},
+		{"suffix range from a stream", map[string]string{"Range": "bytes=-5"}, false, http.StatusPartialContent, data[len(data)-5:], "bytes " + strconv.Itoa(len(data)-5) + "-" + strconv.Itoa(len(data)-1) + "/" + size},
+		{"range with stale If-Range", map[string]string{"Range": "bytes=0-9", "If-Range": `"other"`}, true, http.StatusOK, data, ""},
+		{"unsatisfiable range", map[string]string{"Range": "bytes=100000-"}, true, http.StatusRequestedRangeNotSatisfiable, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := newTestContext(http.MethodGet, "/", "alice")
+			for k, v := range tt.header {
+				c.Request().Header.Set(k, v)
+			}
+			blob, err := h.blobs.Get(context.Background(), img.BlobKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer blob.Close()
+			var r io.Reader = blob
+			if !tt.seekable {
+				r = io.MultiReader(blob)
+			}
+
+			if err := h.serveImage(c, img, r); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want != http.StatusRequestedRangeNotSatisfiable {
+				if got := rec.Header().Get("ETag"); got != etag {
+					t.Errorf("ETag = %q, want %q", got, etag)
+				}
+				if got := rec.Header().Get(echo.HeaderCacheControl); got != immutableCacheControl {
+					t.Errorf("Cache-Control = %q, want %q", got, immutableCacheControl)
+				}
+			}
+			if tt.wantBody != nil && !bytes.Equal(rec.Body.Bytes(), tt.wantBody) {
+				t.Errorf("body = %d bytes, want %d bytes", rec.Body.Len(), len(tt.wantBody))
+			}
+			if tt.want == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("304 body = %d bytes, want empty", rec.Body.Len())
+			}
+			if got := rec.Header().Get("Content-Range"); tt.wantRange != "" && got != tt.wantRange {
+				t.Errorf("Content-Range = %q, want %q", got, tt.wantRange)
+			}
+		})
+	}
+}
+
+func TestServeImageWithEffectIsNotCached(t *testing.T) {
+	h := newTestHandler(t, &fakeRepo{})
+	img, data := storeTestImage(t, h)
+
+	tests := []struct {
+		name      string
+		transform imagefx.Transform
+		wantMime  string
+	}{
+		{"HalfLoaded", imagefx.HalfLoaded(), "image/png"},
+		{"LowQuality", imagefx.LowQuality(5), "image/jpeg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 元の画像の ETag を持つクライアントにも 304 ではなく加工した画像を返す
+			c, rec := newTestContext(http.MethodGet, "/", "alice")
+			c.Request().Header.Set("If-None-Match", `"`+img.Hash+`"`)
+			c.Response().Header().Set("ETag", `"`+img.Hash+`"`)
+			if err := h.serveImageWithEffect(c, img, bytes.NewReader(data), tt.transform); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			if got := rec.Header().Get(echo.HeaderCacheControl); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+			if got := rec.Header().Get("ETag"); got != "" {
+				t.Errorf("ETag = %q, want none", got)
+			}
+			if got := rec.Header().Get(echo.HeaderContentType); got != tt.wantMime {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantMime)
+			}
+			if bytes.Equal(rec.Body.Bytes(), data) {
+				t.Error("body is the original image")
+			}
+			decoded, _, err := image.Decode(rec.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b := decoded.Bounds(); b.Dx() != 8 || b.Dy() != 8 {
+				t.Errorf("size = %v, want 8x8", b)
+			}
+			if _, _, _, a := decoded.At(0, 7).RGBA(); tt.name == "HalfLoaded" && a != 0 {
+				t.Errorf("lower half = %v, want transparent", decoded.At(0, 7))
+			}
+		})
+	}
+}
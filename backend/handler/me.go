@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"cmp"
 	"net/http"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
 	m "github.com/traP-jp/h25s_09/handler/middleware"
@@ -12,7 +14,12 @@ func (h *handler) GetMeHandler(ctx echo.Context) error {
 	if !ok || result == "" {
 		return echo.NewHTTPError(http.StatusUnauthorized)
 	}
-	return ctx.JSON(http.StatusOK, map[string]string{"traqId": result})
+	role, err := h.roleOf(result)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve user role:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve role")
+	}
+	return ctx.JSON(http.StatusOK, map[string]string{"traqId": result, "role": string(role)})
 }
 
 func (h *handler) GetMyAchievementsHandler(ctx echo.Context) error {
@@ -31,3 +38,26 @@ func (h *handler) GetMyAchievementsHandler(ctx echo.Context) error {
 	}
 	return ctx.JSON(http.StatusOK, result)
 }
+
+func (h *handler) GetMyMentionsHandler(ctx echo.Context) error {
+	username := ctx.Get(m.UsernameKey).(string)
+	limit, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("limit"), "20"), 10, 64)
+	if err != nil || limit <= 0 || limit > 100 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit parameter")
+	}
+	offset, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("offset"), "0"), 10, 64)
+	if err != nil || offset < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offset parameter")
+	}
+
+	messages, err := h.repo.GetMessagesMentioning(username, limit, offset)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve mentions:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve mentions")
+	}
+	jsonMessages, err := h.buildMessages(ctx, messages)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, jsonMessages)
+}
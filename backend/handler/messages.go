@@ -2,6 +2,8 @@ package handler
 
 import (
 	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"io"
 	"math/rand/v2"
@@ -23,14 +25,48 @@ type reactions struct {
 	MyReaction bool  `json:"myReaction"`
 }
 
+type reposts struct {
+	Count    int64 `json:"count"`
+	MyRepost bool  `json:"myRepost"`
+}
+
+type mention struct {
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+	Username string `json:"username"`
+}
+
+func toMentions(ms []domain.Mention) []mention {
+	result := make([]mention, len(ms))
+	for i, m := range ms {
+		result[i] = mention{
+			Offset:   m.Offset,
+			Length:   m.Length,
+			Username: m.Username,
+		}
+	}
+	return result
+}
+
 type message struct {
 	ID         uuid.UUID `json:"id"`
 	Author     string    `json:"author"`
 	Content    string    `json:"content"`
 	ImageID    uuid.UUID `json:"imageId,omitempty"`
+	Tags       []string  `json:"tags"`
+	Mentions   []mention `json:"mentions"`
 	Reactions  reactions `json:"reactions"`
-	ReplyCount int64     `json:"replyCount"`
-	CreatedAt  time.Time `json:"createdAt"`
+	Reposts    reposts   `json:"reposts"`
+	Bookmarked bool      `json:"bookmarked"`
+	Pinned     bool      `json:"pinned"`
+	// CreatedByToken はボットなどがパーソナルアクセストークンで投稿したかどうか
+	CreatedByToken bool  `json:"createdByToken"`
+	ReplyCount     int64 `json:"replyCount"`
+	// 投票が付いていなければ null
+	Poll *poll `json:"poll"`
+	// 引用したメッセージ。引用したメッセージの引用先は含めない
+	QuotedMessage *message  `json:"quotedMessage"`
+	CreatedAt     time.Time `json:"createdAt"`
 }
 
 func (h *handler) GetMessagesHandler(ctx echo.Context) error {
@@ -54,60 +90,26 @@ func (h *handler) GetMessagesHandler(ctx echo.Context) error {
 	}
 	traqID := ctx.QueryParam("traqId")
 	includeReplies := ctx.QueryParam("includeReplies") == "true"
+	viewer := ctx.Get(middleware.UsernameKey).(string)
 
 	// Fetch messages from the repository
-	messages, err = h.repo.GetMessages(limit, offset, traqID, includeReplies)
+	messages, err = h.repo.GetMessages(limit, offset, traqID, includeReplies, viewer)
 	if err != nil {
 		ctx.Logger().Error("Failed to retrieve messages:", err)
 		return echo.NewHTTPError(http.StatusInternalServerError)
 	}
 
 	n := len(messages)
-	jsonMessages := make([]message, n)
-	for i, msg := range messages {
-		ImageID, err := h.repo.GetMessageImageIDByMessageID(msg.ID)
-		if err != nil {
-			if !errors.Is(err, domain.ErrNotFound) {
-				ctx.Logger().Error("Failed to retrieve image ID for message:", msg.ID, err)
-				return echo.NewHTTPError(http.StatusInternalServerError)
-			}
-			ImageID = uuid.Nil
-		}
-		Replies, err := h.repo.GetRepliesByMessageID(msg.ID)
-		if err != nil {
-			ctx.Logger().Error("Failed to retrieve replies for message:", msg.ID, err)
-			return echo.NewHTTPError(http.StatusInternalServerError)
-		}
-		RepliesCount := int64(len(Replies))
-		Reactions, err := h.repo.GetReactionsToMessage(msg.ID)
-		if err != nil {
-			ctx.Logger().Error("Failed to retrieve reactions for message:", msg.ID, err)
-			return echo.NewHTTPError(http.StatusInternalServerError)
-		}
-		ReactionsCount := int64(len(Reactions))
-		MyReaction := slices.ContainsFunc(Reactions, func(r *domain.MessageReaction) bool {
-			return r.Username == ctx.Get("username").(string)
-		})
-
-		jsonMessages[i] = message{
-			ID:      msg.ID,
-			Author:  msg.Author,
-			Content: msg.Content,
-			ImageID: ImageID,
-			Reactions: reactions{
-				Count:      ReactionsCount,
-				MyReaction: MyReaction,
-			},
-			ReplyCount: RepliesCount,
-			CreatedAt:  msg.CreatedAt,
-		}
+	jsonMessages, err := h.buildMessages(ctx, messages)
+	if err != nil {
+		return err
 	}
 
 	if utils.DetermineDispatchBug(ctx, h.repo, 12) {
 		rand := rand.IntN(n - 1)
 		jsonMessages[rand+1] = jsonMessages[rand] // "TLでも同じ投稿が2つある"のバグを発生させる
 	}
-	
+
 	shouldDispatch := utils.DetermineDispatchBug(ctx, h.repo, 1)
 	if shouldDispatch {
 		for i := range jsonMessages {
@@ -125,15 +127,168 @@ func (h *handler) GetMessagesHandler(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, jsonMessages)
 }
 
+// buildMessages は domain.Message をレスポンス用の message に変換する
+// 失敗した場合はログを出力し、そのまま返せる *echo.HTTPError を返す
+func (h *handler) buildMessages(ctx echo.Context, messages []domain.Message) ([]message, error) {
+	jsonMessages := make([]message, len(messages))
+	for i, msg := range messages {
+		var err error
+		jsonMessages[i], err = h.buildMessage(ctx, msg, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jsonMessages, nil
+}
+
+// buildMessage は1件のメッセージを message に変換する。withQuote のときは引用したメッセージも含める
+func (h *handler) buildMessage(ctx echo.Context, msg domain.Message, withQuote bool) (message, error) {
+	username := ctx.Get(middleware.UsernameKey).(string)
+
+	ImageID, err := h.repo.GetMessageImageIDByMessageID(msg.ID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			ctx.Logger().Error("Failed to retrieve image ID for message:", msg.ID, err)
+			return message{}, echo.NewHTTPError(http.StatusInternalServerError)
+		}
+		ImageID = uuid.Nil
+	}
+	Replies, err := h.repo.GetRepliesByMessageID(msg.ID)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve replies for message:", msg.ID, err)
+		return message{}, echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	RepliesCount := int64(len(Replies))
+	Reactions, err := h.repo.GetReactionsToMessage(msg.ID)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve reactions for message:", msg.ID, err)
+		return message{}, echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	ReactionsCount := int64(len(Reactions))
+	MyReaction := slices.ContainsFunc(Reactions, func(r *domain.MessageReaction) bool {
+		return r.Username == username
+	})
+	Reposts, err := h.repo.GetRepostsOfMessage(msg.ID)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve reposts for message:", msg.ID, err)
+		return message{}, echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	MyRepost := slices.ContainsFunc(Reposts, func(r *domain.Repost) bool {
+		return r.Username == username
+	})
+	Bookmarked, err := h.repo.IsBookmarked(msg.ID, username)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve bookmark for message:", msg.ID, err)
+		return message{}, echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	Pinned, err := h.repo.IsPinned(msg.ID)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve pin for message:", msg.ID, err)
+		return message{}, echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	Tags, err := h.repo.GetTagsByMessageID(msg.ID)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve tags for message:", msg.ID, err)
+		return message{}, echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	Mentions, err := h.repo.GetMentionsByMessageID(msg.ID)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve mentions for message:", msg.ID, err)
+		return message{}, echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	Poll, err := h.getPoll(ctx, msg.ID)
+	if err != nil {
+		return message{}, err
+	}
+
+	var QuotedMessage *message
+	if withQuote && msg.QuoteOf != uuid.Nil {
+		quoted, err := h.repo.GetMessageByID(msg.QuoteOf)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			ctx.Logger().Error("Failed to retrieve quoted message:", msg.QuoteOf, err)
+			return message{}, echo.NewHTTPError(http.StatusInternalServerError)
+		}
+		// 予約中・非表示の引用元と、見ているユーザーをブロックしている作者の引用元は見つからないものとして扱う
+		if quoted != nil && quoted.Status == domain.MessageStatusPublished {
+			blocked, err := h.repo.IsBlocking(quoted.Author, username)
+			if err != nil {
+				ctx.Logger().Error("Failed to retrieve block state:", err)
+				return message{}, echo.NewHTTPError(http.StatusInternalServerError)
+			}
+			if !blocked {
+				q, err := h.buildMessage(ctx, *quoted, false)
+				if err != nil {
+					return message{}, err
+				}
+				QuotedMessage = &q
+			}
+		}
+	}
+
+	return message{
+		ID:       msg.ID,
+		Author:   msg.Author,
+		Content:  msg.Content,
+		ImageID:  ImageID,
+		Tags:     Tags,
+		Mentions: toMentions(Mentions),
+		Reactions: reactions{
+			Count:      ReactionsCount,
+			MyReaction: MyReaction,
+		},
+		Reposts: reposts{
+			Count:    int64(len(Reposts)),
+			MyRepost: MyRepost,
+		},
+		Bookmarked:     Bookmarked,
+		Pinned:         Pinned,
+		CreatedByToken: msg.TokenID != uuid.Nil,
+		ReplyCount:     RepliesCount,
+		Poll:           Poll,
+		QuotedMessage:  QuotedMessage,
+		CreatedAt:      msg.CreatedAt,
+	}, nil
+}
+
+// contentViolation は本文がルールを満たしていないときのレスポンス。rule で違反したルールがわかる
+type contentViolation struct {
+	Message string `json:"message"`
+	Rule    string `json:"rule"`
+}
+
+// checkContent は本文がルールを満たしていなければ 400 を返す
+// メッセージの編集を実装するときも同じルールで調べる
+func (h *handler) checkContent(content string) error {
+	if v := h.contentRules.Check(content); v != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, contentViolation{Message: v.Message, Rule: v.Rule})
+	}
+	return nil
+}
+
 const MaxImageSize = 16 * 1024 * 1024 // 16 MiB
 
+// MaxScheduleAhead は予約投稿の公開日時をどれだけ先まで指定できるか
+const MaxScheduleAhead = 30 * 24 * time.Hour
+
 func (h *handler) PostMessageHandler(c echo.Context) error {
 	author := c.Get(middleware.UsernameKey).(string)
 
+	suspendedUntil, err := h.repo.GetSuspendedUntil(author)
+	if err != nil {
+		c.Logger().Error("Failed to retrieve suspension:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve suspension")
+	}
+	if suspendedUntil.After(time.Now()) {
+		return echo.NewHTTPError(http.StatusForbidden, "posting is suspended until "+suspendedUntil.UTC().Format(time.RFC3339))
+	}
+
 	content := c.FormValue("message")
 	if content == "" {
 		return echo.NewHTTPError(http.StatusBadRequest, "Message is empty")
 	}
+	if err := h.checkContent(content); err != nil {
+		return err
+	}
 
 	parentIDString := c.FormValue("repliesTo")
 	parentID := uuid.Nil
@@ -160,6 +315,39 @@ func (h *handler) PostMessageHandler(c echo.Context) error {
 		}
 	}
 
+	var publishAt time.Time
+	if publishAtString := c.FormValue("publishAt"); publishAtString != "" {
+		var err error
+		publishAt, err = time.Parse(time.RFC3339, publishAtString)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid publishAt")
+		}
+		if !publishAt.After(time.Now()) {
+			return echo.NewHTTPError(http.StatusBadRequest, "publishAt must be in the future")
+		}
+		if publishAt.After(time.Now().Add(MaxScheduleAhead)) {
+			return echo.NewHTTPError(http.StatusBadRequest, "publishAt is too far in the future")
+		}
+		// DATETIME に合わせて秒未満は切り捨てる
+		publishAt = publishAt.UTC().Truncate(time.Second)
+	}
+
+	pollIn, err := parsePollForm(c, publishAt)
+	if err != nil {
+		return err
+	}
+
+	quoteIDString := c.FormValue("quoteOf")
+	quoteID := uuid.Nil
+	if quoteIDString != "" {
+		var err error
+		quoteID, err = uuid.Parse(quoteIDString)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid quoted message ID")
+		}
+	}
+
+	parentAuthor := ""
 	if parentID != uuid.Nil {
 		parent, err := h.repo.GetMessageByID(parentID)
 		if err != nil {
@@ -169,60 +357,178 @@ func (h *handler) PostMessageHandler(c echo.Context) error {
 			c.Logger().Error(err)
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve parent message")
 		}
+		if parent.Status != domain.MessageStatusPublished {
+			return echo.NewHTTPError(http.StatusNotFound, "Parent message not found")
+		}
 		if parent.ParentID != uuid.Nil {
 			return echo.NewHTTPError(http.StatusBadRequest, "Cannot reply to a reply")
 		}
+		blocked, err := h.repo.IsBlocking(parent.Author, author)
+		if err != nil {
+			c.Logger().Error("Failed to retrieve block state:", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve parent message")
+		}
+		if blocked {
+			return echo.NewHTTPError(http.StatusForbidden, "You are blocked by the author")
+		}
+		parentAuthor = parent.Author
 	}
 
-	var imageData []byte
-	if file != nil {
+	var quoted *domain.Message
+	if quoteID != uuid.Nil {
+		quoted, err = h.repo.GetMessageByID(quoteID)
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				return echo.NewHTTPError(http.StatusNotFound, "Quoted message not found")
+			}
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve quoted message")
+		}
+		if quoted.Status != domain.MessageStatusPublished {
+			return echo.NewHTTPError(http.StatusNotFound, "Quoted message not found")
+		}
+		// 作者にブロックされていれば引用元を見られないので、見つからないものとして扱う
+		blocked, err := h.repo.IsBlocking(quoted.Author, author)
+		if err != nil {
+			c.Logger().Error("Failed to retrieve block state:", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve quoted message")
+		}
+		if blocked {
+			return echo.NewHTTPError(http.StatusNotFound, "Quoted message not found")
+		}
+	}
+
+	// imageHash は参照を増やした画像のBlob。投稿に失敗したら参照を戻す
+	imageHash := ""
+	if file != nil && file.Size != 0 {
 		fileReader, err := file.Open()
 		if err != nil {
 			c.Logger().Error(err)
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to open image file")
 		}
 		defer fileReader.Close()
-		imageData, err = io.ReadAll(fileReader)
-		if err != nil {
+		hasher := sha256.New()
+		if _, err := io.Copy(hasher, fileReader); err != nil {
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read image file")
+		}
+		hash := hex.EncodeToString(hasher.Sum(nil))
+		if _, err := fileReader.Seek(0, io.SeekStart); err != nil {
 			c.Logger().Error(err)
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read image file")
 		}
+		// 同じ内容の画像が既に保存されていればアップロードしない
+		mime := file.Header.Get("Content-Type")
+		err = h.repo.AcquireImageBlob(hash, hash, mime, file.Size, func() error {
+			return h.blobs.Put(c.Request().Context(), hash, fileReader, file.Size, mime)
+		})
+		if err != nil {
+			c.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save image")
+		}
+		imageHash = hash
 	}
 
-	msg, err := h.repo.CreateMessage(author, content, parentID)
+	msg, err := h.repo.CreateMessage(author, content, parentID, quoteID, publishAt, tokenIDOf(c), pollIn)
 	if err != nil {
 		c.Logger().Error(err)
+		if imageHash != "" {
+			h.releaseImageBlob(c, imageHash)
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create message")
 	}
 	imgID := uuid.Nil
-	if file != nil && len(imageData) != 0 {
-		img, err := h.repo.CreateMessageImage(msg.ID, imageData, file.Header.Get("Content-Type"))
+	if imageHash != "" {
+		img, err := h.repo.CreateMessageImage(msg.ID, imageHash)
 		if err != nil {
 			c.Logger().Error(err)
+			h.releaseImageBlob(c, imageHash)
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save image")
 		}
 		imgID = img.ID
 	}
+	var createdPoll *poll
+	if pollIn != nil {
+		createdPoll, err = h.getPoll(c, msg.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if msg.Status == domain.MessageStatusScheduled {
+		// 通知は公開されたときにスケジューラーが送る
+		h.wakeScheduler()
+	} else {
+		h.notifyNewMessage(c.Logger(), msg, parentAuthor)
+	}
+
+	var quotedMessage *message
+	if quoted != nil {
+		q, err := h.buildMessage(c, *quoted, false)
+		if err != nil {
+			return err
+		}
+		quotedMessage = &q
+	}
 
 	return c.JSON(http.StatusOK, &messageDetail{
-		ID:        msg.ID,
-		Author:    msg.Author,
-		Content:   msg.Content,
-		ImageID:   imgID,
-		Reactions: reactions{Count: 0, MyReaction: false},
-		Replies:   []message{},
-		CreatedAt: msg.CreatedAt,
+		ID:             msg.ID,
+		Author:         msg.Author,
+		Content:        msg.Content,
+		ImageID:        imgID,
+		Tags:           domain.ExtractHashtags(msg.Content),
+		Mentions:       toMentions(domain.ExtractMentions(msg.Content)),
+		Reactions:      reactions{Count: 0, MyReaction: false},
+		Reposts:        reposts{Count: 0, MyRepost: false},
+		Replies:        []message{},
+		CreatedByToken: msg.TokenID != uuid.Nil,
+		Poll:           createdPoll,
+		QuotedMessage:  quotedMessage,
+		Status:         string(msg.Status),
+		PublishAt:      publishAtPtr(msg),
+		CreatedAt:      msg.CreatedAt,
 	})
 }
 
 type messageDetail struct {
-	ID        uuid.UUID `json:"id"`
-	Author    string    `json:"author"`
-	Content   string    `json:"content"`
-	ImageID   uuid.UUID `json:"imageId,omitempty"`
-	Reactions reactions `json:"reactions"`
-	Replies   []message `json:"replies"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID             uuid.UUID `json:"id"`
+	Author         string    `json:"author"`
+	Content        string    `json:"content"`
+	ImageID        uuid.UUID `json:"imageId,omitempty"`
+	Tags           []string  `json:"tags"`
+	Mentions       []mention `json:"mentions"`
+	Reactions      reactions `json:"reactions"`
+	Reposts        reposts   `json:"reposts"`
+	Bookmarked     bool      `json:"bookmarked"`
+	Pinned         bool      `json:"pinned"`
+	CreatedByToken bool      `json:"createdByToken"`
+	Replies        []message `json:"replies"`
+	Poll           *poll     `json:"poll"`
+	QuotedMessage  *message  `json:"quotedMessage"`
+	// Status と PublishAt は投稿したときのレスポンスにだけ含める
+	Status    string     `json:"status,omitempty"`
+	PublishAt *time.Time `json:"publishAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+func newMessageDetail(m message, replies []message) *messageDetail {
+	return &messageDetail{
+		ID:             m.ID,
+		Author:         m.Author,
+		Content:        m.Content,
+		ImageID:        m.ImageID,
+		Tags:           m.Tags,
+		Mentions:       m.Mentions,
+		Reactions:      m.Reactions,
+		Reposts:        m.Reposts,
+		Bookmarked:     m.Bookmarked,
+		Pinned:         m.Pinned,
+		CreatedByToken: m.CreatedByToken,
+		Replies:        replies,
+		Poll:           m.Poll,
+		QuotedMessage:  m.QuotedMessage,
+		CreatedAt:      m.CreatedAt,
+	}
 }
 
 func (h *handler) GetMessageHandler(c echo.Context) error {
@@ -239,126 +545,75 @@ func (h *handler) GetMessageHandler(c echo.Context) error {
 		c.Logger().Error("Failed to retrieve message:", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve message")
 	}
+	// 公開前の予約投稿や非表示にされたメッセージは作者とモデレーターにしか見せない
+	if username := c.Get(middleware.UsernameKey).(string); msg.Status != domain.MessageStatusPublished && msg.Author != username {
+		role, err := h.roleOf(username)
+		if err != nil {
+			c.Logger().Error("Failed to retrieve user role:", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve message")
+		}
+		if !role.AtLeast(domain.RoleModerator) {
+			return echo.NewHTTPError(http.StatusNotFound, "Message not found")
+		}
+	}
 
-	imageID, err := h.repo.GetMessageImageIDByMessageID(ID)
+	// ブロックされているユーザーには作者のメッセージを見せない
+	viewer := c.Get(middleware.UsernameKey).(string)
+	hiddenUsers, err := h.repo.GetHiddenUsers(viewer)
 	if err != nil {
-		if !errors.Is(err, domain.ErrNotFound) {
-			c.Logger().Error("Failed to retrieve image ID for message:", ID, err)
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve image ID")
-		}
-		imageID = uuid.Nil
-		c.Logger().Info("No image found for message:", ID)
+		c.Logger().Error("Failed to retrieve hidden users:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve message")
+	}
+	blocked, err := h.repo.IsBlocking(msg.Author, viewer)
+	if err != nil {
+		c.Logger().Error("Failed to retrieve block state:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve message")
+	}
+	if blocked {
+		return echo.NewHTTPError(http.StatusNotFound, "Message not found")
 	}
 
-	reactionList, err := h.repo.GetReactionsToMessage(ID)
+	detail, err := h.buildMessage(c, *msg, true)
 	if err != nil {
-		c.Logger().Error("Failed to retrieve reactions for message:", ID, err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve reactions")
+		return err
 	}
-	reactionsCount := int64(len(reactionList))
-	myReaction := slices.ContainsFunc(reactionList, func(r *domain.MessageReaction) bool {
-		return r.Username == c.Get("username").(string)
-	})
 
 	replies, err := h.repo.GetRepliesByMessageID(ID)
 	if err != nil {
 		c.Logger().Error("Failed to retrieve replies for message:", ID, err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve replies")
 	}
+	// ミュート・ブロックしているユーザーの返信は表示しない
+	replies = slices.DeleteFunc(replies, func(reply *domain.Message) bool {
+		return slices.Contains(hiddenUsers, reply.Author)
+	})
 
 	repliesList := make([]message, 0, len(replies)*20)
 	for _, reply := range replies {
-		replyImageID, err := h.repo.GetMessageImageIDByMessageID(reply.ID)
+		replyMessage, err := h.buildMessage(c, *reply, true)
 		if err != nil {
-			if !errors.Is(err, domain.ErrNotFound) {
-				c.Logger().Error("Failed to retrieve image ID for reply:", reply.ID, err)
-				return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve reply image ID")
-			}
-			replyImageID = uuid.Nil
+			return err
 		}
-		replyReactionList, err := h.repo.GetReactionsToMessage(reply.ID)
-		if err != nil {
-			c.Logger().Error("Failed to retrieve reactions for reply:", reply.ID, err)
-			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to retrieve reply reactions")
-		}
-
-		repliesList = append(repliesList, message{
-			ID:      reply.ID,
-			Author:  reply.Author,
-			Content: reply.Content,
-			ImageID: replyImageID,
-			Reactions: reactions{
-				Count: int64(len(replyReactionList)),
-				MyReaction: slices.ContainsFunc(replyReactionList, func(r *domain.MessageReaction) bool {
-					return r.Username == c.Get("username").(string)
-				}),
-			},
-			CreatedAt: reply.CreatedAt,
-		})
+		repliesList = append(repliesList, replyMessage)
 
 		duplicateCount := 0
 		shouldDispatch := utils.DetermineDispatchBug(c, h.repo, 100)
 		for shouldDispatch && duplicateCount < 20 {
 			duplicateCount++
 			shouldDispatch = utils.DetermineDispatchBug(c, h.repo, 7)
-			repliesList = append(repliesList, message{
-				ID:      reply.ID,
-				Author:  reply.Author,
-				Content: reply.Content,
-				ImageID: replyImageID,
-				Reactions: reactions{
-					Count: int64(len(replyReactionList)),
-					MyReaction: slices.ContainsFunc(replyReactionList, func(r *domain.MessageReaction) bool {
-						return r.Username == c.Get("username").(string)
-					}),
-				},
-				CreatedAt: reply.CreatedAt,
-			})
+			repliesList = append(repliesList, replyMessage)
 		}
 	}
 
 	if utils.DetermineDispatchBug(c, h.repo, 1) {
-		msg.CreatedAt = time.Now().AddDate(0, 0, -1)
-		return c.JSON(http.StatusOK, &messageDetail{
-			ID:      ID,
-			Author:  msg.Author,
-			Content: msg.Content,
-			ImageID: imageID,
-			Reactions: reactions{
-				Count:      reactionsCount,
-				MyReaction: myReaction,
-			},
-			Replies:   repliesList,
-			CreatedAt: msg.CreatedAt,
-		})
+		detail.CreatedAt = time.Now().AddDate(0, 0, -1)
+		return c.JSON(http.StatusOK, newMessageDetail(detail, repliesList))
 	}
 
 	if utils.DetermineDispatchBug(c, h.repo, 1) {
-		msg.CreatedAt = time.Now().Add(30 * time.Minute)
-		return c.JSON(http.StatusOK, &messageDetail{
-			ID:      ID,
-			Author:  msg.Author,
-			Content: msg.Content,
-			ImageID: imageID,
-			Reactions: reactions{
-				Count:      reactionsCount,
-				MyReaction: myReaction,
-			},
-			Replies:   repliesList,
-			CreatedAt: msg.CreatedAt,
-		})
+		detail.CreatedAt = time.Now().Add(30 * time.Minute)
+		return c.JSON(http.StatusOK, newMessageDetail(detail, repliesList))
 	}
 
-	return c.JSON(http.StatusOK, &messageDetail{
-		ID:      ID,
-		Author:  msg.Author,
-		Content: msg.Content,
-		ImageID: imageID,
-		Reactions: reactions{
-			Count:      reactionsCount,
-			MyReaction: myReaction,
-		},
-		Replies:   repliesList,
-		CreatedAt: msg.CreatedAt,
-	})
+	return c.JSON(http.StatusOK, newMessageDetail(detail, repliesList))
 }
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+func TestBuildMessageHidesUnpublishedQuotes(t *testing.T) {
+	repo := &messageRepo{messages: map[uuid.UUID]*domain.Message{}}
+	h := newTestHandler(t, repo)
+
+	tests := []struct {
+		status    domain.MessageStatus
+		wantQuote bool
+	}{
+		{domain.MessageStatusPublished, true},
+		{domain.MessageStatusScheduled, false},
+		{domain.MessageStatusCanceled, false},
+		{domain.MessageStatusHidden, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			quoted := &domain.Message{ID: uuid.New(), Author: "bob", Content: "secret", Status: tt.status}
+			repo.messages[quoted.ID] = quoted
+			msg := domain.Message{ID: uuid.New(), Author: "alice", Content: "look", QuoteOf: quoted.ID, Status: domain.MessageStatusPublished}
+
+			c, _ := newTestContext(http.MethodGet, "/", "carol")
+			built, err := h.buildMessage(c, msg, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := built.QuotedMessage != nil; got != tt.wantQuote {
+				t.Fatalf("quoted message included = %v, want %v", got, tt.wantQuote)
+			}
+		})
+	}
+}
+
+func TestBuildMessageHidesQuotesFromBlockedViewers(t *testing.T) {
+	quoted := &domain.Message{ID: uuid.New(), Author: "bob", Content: "secret", Status: domain.MessageStatusPublished}
+	repo := &messageRepo{
+		messages: map[uuid.UUID]*domain.Message{quoted.ID: quoted},
+		blocks:   map[[2]string]bool{{"bob", "carol"}: true},
+	}
+	h := newTestHandler(t, repo)
+	msg := domain.Message{ID: uuid.New(), Author: "alice", Content: "look", QuoteOf: quoted.ID, Status: domain.MessageStatusPublished}
+
+	tests := []struct {
+		viewer    string
+		wantQuote bool
+	}{
+		{"carol", false}, // bob にブロックされている
+		{"dave", true},
+		{"bob", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.viewer, func(t *testing.T) {
+			c, _ := newTestContext(http.MethodGet, "/", tt.viewer)
+			built, err := h.buildMessage(c, msg, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := built.QuotedMessage != nil; got != tt.wantQuote {
+				t.Fatalf("quoted message included = %v, want %v", got, tt.wantQuote)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// ErrNoCredentials はリクエストにその Authenticator が扱う認証情報が含まれていないことを表す
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials は認証情報が含まれているが正しくないことを表す
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Identity は認証されたユーザー
+type Identity struct {
+	Username string
+	// Provider は認証に使った Authenticator の名前
+	Provider string
+	// TokenID と Scopes はパーソナルアクセストークンで認証したときだけ設定される
+	TokenID uuid.UUID
+	Scopes  []string
+}
+
+type Authenticator interface {
+	// Authenticate はリクエストを送ったユーザーを返す
+	// 認証情報がなければ ErrNoCredentials を、正しくなければそれ以外のエラーを返す
+	Authenticate(c echo.Context) (*Identity, error)
+}
+
+// MinSessionSecretLength は SESSION_SECRET の最小の長さ (バイト)
+// OIDC ではセッションがログインの証明になるので、推測できない長さの鍵を求める
+const MinSessionSecretLength = 32
+
+// SessionSecretFromEnv はセッションの署名に使う SESSION_SECRET を返す
+// 設定されていなければ、開発環境では固定の値を、それ以外では起動ごとに作る乱数を使う
+// (乱数の場合は再起動するとセッションが無効になる)
+func SessionSecretFromEnv() []byte {
+	if secret := os.Getenv("SESSION_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	if os.Getenv("ENVIRONMENT") == DevelopmentEnv {
+		return []byte("secret")
+	}
+	secret := make([]byte, MinSessionSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}
+
+// Authenticators は設定された Authenticator の一覧
+type Authenticators struct {
+	List []Authenticator
+	// OIDC はログイン用のハンドラーを登録するために使う。使わない設定なら nil
+	OIDC *OIDCAuthenticator
+	// Development は開発環境でだけ設定される
+	Development *DevelopmentAuthenticator
+}
+
+// NewAuthenticatorsFromEnv は AUTH_PROVIDERS (カンマ区切り、既定値は "forward") に並べた順に Authenticator を作る
+//   - forward: 認証プロキシの X-Forwarded-User (TRUSTED_PROXY_CIDRS, FORWARD_AUTH_SECRET)。
+//     開発環境以外ではどちらかの設定が必要
+//   - oidc: OAuth2/OIDC の認可コードフローでログインしたセッション (OIDC_*)。
+//     開発環境以外では MinSessionSecretLength バイト以上の SESSION_SECRET が必要
+//   - bearer: Authorization: Bearer の設定で決めたトークン (AUTH_BEARER_TOKENS)
+//
+// personalTokens で調べるパーソナルアクセストークンは設定によらず最初に試す
+// ENVIRONMENT=development のときは最後に DevelopmentAuthenticator を加える
+func NewAuthenticatorsFromEnv(personalTokens TokenResolver) (*Authenticators, error) {
+	providers := os.Getenv("AUTH_PROVIDERS")
+	if providers == "" {
+		providers = "forward"
+	}
+	bearer := &BearerAuthenticator{Resolver: personalTokens}
+	result := &Authenticators{List: []Authenticator{bearer}}
+	for _, name := range strings.Split(providers, ",") {
+		switch strings.TrimSpace(name) {
+		case "forward":
+			if os.Getenv("TRUSTED_PROXY_CIDRS") == "" && os.Getenv("FORWARD_AUTH_SECRET") == "" && os.Getenv("ENVIRONMENT") != DevelopmentEnv {
+				return nil, fmt.Errorf("TRUSTED_PROXY_CIDRS or FORWARD_AUTH_SECRET must be set for the forward auth provider")
+			}
+			verifier, err := NewProxyVerifierFromEnv()
+			if err != nil {
+				return nil, err
+			}
+			result.List = append(result.List, &ForwardAuthAuthenticator{Verifier: verifier})
+		case "oidc":
+			if len(os.Getenv("SESSION_SECRET")) < MinSessionSecretLength && os.Getenv("ENVIRONMENT") != DevelopmentEnv {
+				return nil, fmt.Errorf("SESSION_SECRET must be at least %d bytes for the oidc auth provider", MinSessionSecretLength)
+			}
+			oidc, err := NewOIDCAuthenticatorFromEnv()
+			if err != nil {
+				return nil, err
+			}
+			result.OIDC = oidc
+			result.List = append(result.List, oidc)
+		case "bearer":
+			resolver, err := NewStaticTokenResolverFromEnv()
+			if err != nil {
+				return nil, err
+			}
+			bearer.Resolver = ChainTokenResolver{personalTokens, resolver}
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown auth provider %q", name)
+		}
+	}
+	if os.Getenv("ENVIRONMENT") == DevelopmentEnv {
+		result.Development = &DevelopmentAuthenticator{}
+		result.List = append(result.List, result.Development)
+	}
+	return result, nil
+}
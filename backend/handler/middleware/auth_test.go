@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewAuthenticatorsFromEnvRequiresSessionSecretForOIDC(t *testing.T) {
+	tests := []struct {
+		name        string
+		providers   string
+		secret      string
+		environment string
+		wantErr     bool
+	}{
+		{"oidc without secret", "oidc", "", "", true},
+		{"oidc with short secret", "oidc", "short", "", true},
+		{"oidc with long secret", "oidc", strings.Repeat("s", MinSessionSecretLength), "", false},
+		{"oidc in development", "oidc", "", DevelopmentEnv, false},
+		{"forward without secret", "forward", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXY_CIDRS", "10.0.0.0/8")
+			t.Setenv("AUTH_PROVIDERS", tt.providers)
+			t.Setenv("SESSION_SECRET", tt.secret)
+			t.Setenv("ENVIRONMENT", tt.environment)
+			t.Setenv("OIDC_ISSUER", "https://idp.example.com")
+			t.Setenv("OIDC_CLIENT_ID", "client")
+			t.Setenv("OIDC_REDIRECT_URL", "https://app.example.com/api/auth/callback")
+			_, err := NewAuthenticatorsFromEnv(nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewAuthenticatorsFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewAuthenticatorsFromEnvRequiresProxyConfigForForward(t *testing.T) {
+	tests := []struct {
+		name        string
+		cidrs       string
+		secret      string
+		environment string
+		wantErr     bool
+	}{
+		{"nothing configured", "", "", "", true},
+		{"trusted proxies", "10.0.0.0/8", "", "", false},
+		{"no trusted proxies", "none", "", "", false},
+		{"signature secret", "", "secret", "", false},
+		{"development", "", "", DevelopmentEnv, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AUTH_PROVIDERS", "forward")
+			t.Setenv("TRUSTED_PROXY_CIDRS", tt.cidrs)
+			t.Setenv("FORWARD_AUTH_SECRET", tt.secret)
+			t.Setenv("ENVIRONMENT", tt.environment)
+			_, err := NewAuthenticatorsFromEnv(nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewAuthenticatorsFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSessionSecretFromEnv(t *testing.T) {
+	t.Run("configured", func(t *testing.T) {
+		t.Setenv("SESSION_SECRET", "configured")
+		if got := string(SessionSecretFromEnv()); got != "configured" {
+			t.Fatalf("SessionSecretFromEnv() = %q, want %q", got, "configured")
+		}
+	})
+	t.Run("development fallback", func(t *testing.T) {
+		t.Setenv("SESSION_SECRET", "")
+		t.Setenv("ENVIRONMENT", DevelopmentEnv)
+		if got := string(SessionSecretFromEnv()); got != "secret" {
+			t.Fatalf("SessionSecretFromEnv() = %q, want the development fallback", got)
+		}
+	})
+	t.Run("random outside development", func(t *testing.T) {
+		t.Setenv("SESSION_SECRET", "")
+		t.Setenv("ENVIRONMENT", "production")
+		a, b := SessionSecretFromEnv(), SessionSecretFromEnv()
+		if len(a) < MinSessionSecretLength || string(a) == string(b) || string(a) == "secret" {
+			t.Fatalf("SessionSecretFromEnv() = %q, %q, want different random secrets", a, b)
+		}
+	})
+}
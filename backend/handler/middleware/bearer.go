@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TokenResolver は Bearer トークンの持ち主を調べる
+type TokenResolver interface {
+	// ResolveToken は知らないトークンなら ErrInvalidCredentials を返す
+	ResolveToken(ctx context.Context, token string) (*Identity, error)
+}
+
+// BearerAuthenticator は Authorization: Bearer のトークンを使う
+type BearerAuthenticator struct {
+	Resolver TokenResolver
+}
+
+func (a *BearerAuthenticator) Authenticate(c echo.Context) (*Identity, error) {
+	scheme, token, ok := strings.Cut(c.Request().Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, ErrNoCredentials
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, ErrInvalidCredentials
+	}
+	return a.Resolver.ResolveToken(c.Request().Context(), token)
+}
+
+// ChainTokenResolver は前から順にトークンを調べ、最初に見つかった持ち主を返す
+type ChainTokenResolver []TokenResolver
+
+func (chain ChainTokenResolver) ResolveToken(ctx context.Context, token string) (*Identity, error) {
+	for _, r := range chain {
+		identity, err := r.ResolveToken(ctx, token)
+		if errors.Is(err, ErrInvalidCredentials) {
+			continue
+		}
+		return identity, err
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// StaticTokenResolver は設定で決めたトークンを使う
+// トークンはハッシュにして持ち、比較の時間から中身がわからないようにする
+type StaticTokenResolver struct {
+	users map[[sha256.Size]byte]string
+}
+
+// NewStaticTokenResolverFromEnv は AUTH_BEARER_TOKENS ("username:token" のカンマ区切り) を読む
+func NewStaticTokenResolverFromEnv() (*StaticTokenResolver, error) {
+	r := &StaticTokenResolver{users: map[[sha256.Size]byte]string{}}
+	for _, entry := range strings.Split(os.Getenv("AUTH_BEARER_TOKENS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		username, token, ok := strings.Cut(entry, ":")
+		if !ok || username == "" || token == "" {
+			return nil, fmt.Errorf("invalid AUTH_BEARER_TOKENS entry for %q", username)
+		}
+		r.users[sha256.Sum256([]byte(token))] = username
+	}
+	return r, nil
+}
+
+func (r *StaticTokenResolver) ResolveToken(_ context.Context, token string) (*Identity, error) {
+	username, ok := r.users[sha256.Sum256([]byte(token))]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return &Identity{Username: username, Provider: "bearer"}, nil
+}
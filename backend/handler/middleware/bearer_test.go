@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// mapTokenResolver はトークンと持ち主の対応をそのまま持つ
+type mapTokenResolver map[string]string
+
+func (r mapTokenResolver) ResolveToken(_ context.Context, token string) (*Identity, error) {
+	username, ok := r[token]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return &Identity{Username: username, Provider: "map"}, nil
+}
+
+func TestNewStaticTokenResolverFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"entries", "alice:token-a, bob:token-b", false},
+		{"missing token", "alice:", true},
+		{"missing separator", "alice", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AUTH_BEARER_TOKENS", tt.env)
+			if _, err := NewStaticTokenResolverFromEnv(); (err != nil) != tt.wantErr {
+				t.Fatalf("NewStaticTokenResolverFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBearerAuthenticator(t *testing.T) {
+	t.Setenv("AUTH_BEARER_TOKENS", "alice:static-token")
+	static, err := NewStaticTokenResolverFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &BearerAuthenticator{Resolver: ChainTokenResolver{mapTokenResolver{"personal-token": "bob"}, static}}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantUser      string
+		wantErr       error
+	}{
+		{"no header", "", "", ErrNoCredentials},
+		{"other scheme", "Basic YWxpY2U6cGFzcw==", "", ErrNoCredentials},
+		{"empty token", "Bearer  ", "", ErrInvalidCredentials},
+		{"unknown token", "Bearer nope", "", ErrInvalidCredentials},
+		{"static token", "Bearer static-token", "alice", nil},
+		{"scheme is case-insensitive", "bearer static-token", "alice", nil},
+		{"first resolver wins", "Bearer personal-token", "bob", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+			identity, err := a.Authenticate(c)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if identity.Username != tt.wantUser {
+				t.Fatalf("Authenticate() = %q, want %q", identity.Username, tt.wantUser)
+			}
+		})
+	}
+}
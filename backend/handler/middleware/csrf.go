@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
+)
+
+const (
+	// CSRFContextKey にリクエストの CSRF トークンが入る
+	CSRFContextKey = "csrf"
+	// CSRFFormField はフォームで CSRF トークンを送るときの項目名
+	CSRFFormField = "_csrf"
+	// CSRFCookie は axios が自動で読んで X-XSRF-TOKEN ヘッダーに入れる Cookie の名前
+	CSRFCookie = "XSRF-TOKEN"
+)
+
+// CSRFProtection は Cookie で認証されるリクエストをダブルサブミットトークンで守る
+// GET 以外のリクエストでは、Cookie の XSRF-TOKEN と同じ値を X-CSRF-Token か X-XSRF-TOKEN ヘッダー、
+// または _csrf フォーム項目で送る必要がある
+// Authorization: Bearer のようにブラウザが勝手に付けないヘッダーで認証するリクエストは調べない
+func CSRFProtection() echo.MiddlewareFunc {
+	return echomw.CSRFWithConfig(echomw.CSRFConfig{
+		Skipper:     usesHeaderCredentials,
+		TokenLookup: "header:" + echo.HeaderXCSRFToken + ",header:X-XSRF-TOKEN,form:" + CSRFFormField,
+		ContextKey:  CSRFContextKey,
+		CookieName:  CSRFCookie,
+		CookiePath:  "/",
+		// フロントエンドの JavaScript から読めるように HttpOnly にはしない
+		CookieSameSite: http.SameSiteLaxMode,
+	})
+}
+
+// usesHeaderCredentials はリクエストが Cookie ではなくヘッダーで認証されるかどうか
+// 他のサイトからのフォーム送信ではこれらのヘッダーを付けられないので CSRF は起きない
+func usesHeaderCredentials(c echo.Context) bool {
+	scheme, _, ok := strings.Cut(c.Request().Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return true
+	}
+	return os.Getenv("ENVIRONMENT") == DevelopmentEnv && c.Request().Header.Get(DevUserHeader) != ""
+}
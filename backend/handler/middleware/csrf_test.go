@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestUsesHeaderCredentials(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment string
+		header      map[string]string
+		want        bool
+	}{
+		{"no credentials", "", nil, false},
+		{"bearer", "", map[string]string{"Authorization": "Bearer token"}, true},
+		{"lowercase bearer", "", map[string]string{"Authorization": "bearer token"}, true},
+		{"bearer without token", "", map[string]string{"Authorization": "Bearer"}, false},
+		{"basic", "", map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, false},
+		{"dev user in development", DevelopmentEnv, map[string]string{DevUserHeader: "alice"}, true},
+		{"dev user in production", "production", map[string]string{DevUserHeader: "alice"}, false},
+		{"empty dev user in development", DevelopmentEnv, map[string]string{DevUserHeader: ""}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENVIRONMENT", tt.environment)
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+			if got := usesHeaderCredentials(c); got != tt.want {
+				t.Fatalf("usesHeaderCredentials() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRFProtection(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "")
+	e := echo.New()
+	e.Use(CSRFProtection())
+	e.POST("/", func(c echo.Context) error { return c.NoContent(http.StatusNoContent) })
+
+	const token = "0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		name   string
+		cookie string
+		header map[string]string
+		want   int
+	}{
+		{"missing token", "", nil, http.StatusBadRequest},
+		{"cookie only", token, nil, http.StatusBadRequest},
+		{"mismatched token", token, map[string]string{echo.HeaderXCSRFToken: "wrong"}, http.StatusForbidden},
+		{"X-CSRF-Token", token, map[string]string{echo.HeaderXCSRFToken: token}, http.StatusNoContent},
+		{"X-XSRF-TOKEN", token, map[string]string{"X-XSRF-TOKEN": token}, http.StatusNoContent},
+		{"bearer skips the check", "", map[string]string{"Authorization": "Bearer token"}, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: CSRFCookie, Value: tt.cookie})
+			}
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"regexp"
+
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// DevUserHeader と DevUserQuery で開発環境でなりすますユーザーを指定できる
+	// クエリパラメータで指定したユーザーはセッションに保存され、以降のリクエストでも使われる
+	DevUserHeader = "X-Dev-User"
+	DevUserQuery  = "devUser"
+
+	devSessionKey = "dev"
+)
+
+var devUsernamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// DevelopmentAuthenticator は開発環境で任意のユーザーになりすませるようにする
+// ENVIRONMENT=development のときだけ使われる
+type DevelopmentAuthenticator struct{}
+
+func (a *DevelopmentAuthenticator) Authenticate(c echo.Context) (*Identity, error) {
+	if username := c.Request().Header.Get(DevUserHeader); username != "" {
+		return devIdentity(username)
+	}
+	if username := c.QueryParam(DevUserQuery); username != "" {
+		identity, err := devIdentity(username)
+		if err != nil {
+			return nil, err
+		}
+		if err := saveDevUser(c, username); err != nil {
+			c.Logger().Error("Failed to save development user:", err)
+		}
+		return identity, nil
+	}
+	sess, err := session.Get(devSessionKey, c)
+	if err != nil {
+		return nil, ErrNoCredentials
+	}
+	username, ok := sess.Values[sessionUsername].(string)
+	if !ok || username == "" {
+		return nil, ErrNoCredentials
+	}
+	return devIdentity(username)
+}
+
+func devIdentity(username string) (*Identity, error) {
+	if !devUsernamePattern.MatchString(username) {
+		return nil, fmt.Errorf("%w: invalid development username %q", ErrInvalidCredentials, username)
+	}
+	return &Identity{Username: username, Provider: "development"}, nil
+}
+
+func saveDevUser(c echo.Context, username string) error {
+	sess, err := session.Get(devSessionKey, c)
+	if err != nil {
+		return err
+	}
+	if username == "" {
+		delete(sess.Values, sessionUsername)
+	} else {
+		sess.Values[sessionUsername] = username
+	}
+	return sess.Save(c.Request(), c.Response())
+}
+
+var devLoginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head><meta charset="utf-8"><title>開発用ログイン</title></head>
+<body>
+<h1>開発用ログイン</h1>
+<p>現在のユーザー: {{if .Current}}<b>{{.Current}}</b>{{else}}なし (anonymous){{end}}</p>
+<form method="post" action="/api/dev/login">
+  <input type="hidden" name="_csrf" value="{{.CSRFToken}}">
+  <input name="username" placeholder="traQ ID" pattern="[A-Za-z0-9_\-]{1,32}" required autofocus>
+  <input type="hidden" name="redirect" value="{{.Redirect}}">
+  <button type="submit">このユーザーになる</button>
+</form>
+<form method="post" action="/api/dev/logout">
+  <input type="hidden" name="_csrf" value="{{.CSRFToken}}">
+  <input type="hidden" name="redirect" value="{{.Redirect}}">
+  <button type="submit">ログアウト</button>
+</form>
+</body>
+</html>
+`))
+
+// DevLoginPageHandler はなりすますユーザーを選ぶ画面を表示する
+func (a *DevelopmentAuthenticator) DevLoginPageHandler(c echo.Context) error {
+	current := ""
+	if sess, err := session.Get(devSessionKey, c); err == nil {
+		current, _ = sess.Values[sessionUsername].(string)
+	}
+	csrfToken, _ := c.Get(CSRFContextKey).(string)
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextHTMLCharsetUTF8)
+	c.Response().WriteHeader(http.StatusOK)
+	return devLoginPage.Execute(c.Response(), map[string]string{
+		"Current":   current,
+		"Redirect":  safeReturnTo(c.QueryParam("redirect")),
+		"CSRFToken": csrfToken,
+	})
+}
+
+func (a *DevelopmentAuthenticator) DevLoginHandler(c echo.Context) error {
+	username := c.FormValue("username")
+	if !devUsernamePattern.MatchString(username) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid username")
+	}
+	if err := saveDevUser(c, username); err != nil {
+		c.Logger().Error("Failed to save development user:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to save session")
+	}
+	return c.Redirect(http.StatusSeeOther, safeReturnTo(c.FormValue("redirect")))
+}
+
+func (a *DevelopmentAuthenticator) DevLogoutHandler(c echo.Context) error {
+	if err := saveDevUser(c, ""); err != nil {
+		c.Logger().Error("Failed to save development user:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to save session")
+	}
+	return c.Redirect(http.StatusSeeOther, safeReturnTo(c.FormValue("redirect")))
+}
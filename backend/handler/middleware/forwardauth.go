@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ForwardAuthAuthenticator は認証プロキシが付けた X-Forwarded-User を使う
+// 信頼できない相手から送られてきたヘッダーはなりすましとして拒否する
+type ForwardAuthAuthenticator struct {
+	Verifier *ProxyVerifier
+}
+
+func (a *ForwardAuthAuthenticator) Authenticate(c echo.Context) (*Identity, error) {
+	username := c.Request().Header.Get("X-Forwarded-User")
+	if username == "" {
+		return nil, ErrNoCredentials
+	}
+	if ok, reason := a.Verifier.Verify(c.Request(), username); !ok {
+		return nil, fmt.Errorf("%w: spoofed X-Forwarded-User %q: %s", ErrInvalidCredentials, username, reason)
+	}
+	return &Identity{Username: username, Provider: "forward"}, nil
+}
@@ -0,0 +1,307 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+// AuthSessionKey はログインしたユーザーを保存するセッションの名前
+const AuthSessionKey = "auth"
+
+const (
+	sessionUsername = "username"
+	sessionState    = "oidc_state"
+	sessionVerifier = "oidc_verifier"
+	sessionReturnTo = "oidc_return_to"
+
+	authSessionMaxAge = 7 * 24 * 60 * 60 // 7日
+)
+
+type OIDCConfig struct {
+	// Issuer の /.well-known/openid-configuration から各エンドポイントを調べる
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// UsernameClaim は userinfo のどの項目をユーザー名として使うか
+	UsernameClaim string
+}
+
+// OIDCAuthenticator は OAuth2/OIDC の認可コードフロー (PKCE付き) でログインしたセッションを使う
+type OIDCAuthenticator struct {
+	config OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	endpoints *oidcEndpoints
+}
+
+type oidcEndpoints struct {
+	Authorization string `json:"authorization_endpoint"`
+	Token         string `json:"token_endpoint"`
+	UserInfo      string `json:"userinfo_endpoint"`
+}
+
+// NewOIDCAuthenticatorFromEnv は OIDC_ISSUER, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET, OIDC_REDIRECT_URL,
+// OIDC_SCOPES (スペース区切り、既定値は "openid profile"), OIDC_USERNAME_CLAIM (既定値は "preferred_username") を読む
+func NewOIDCAuthenticatorFromEnv() (*OIDCAuthenticator, error) {
+	config := OIDCConfig{
+		Issuer:        strings.TrimSuffix(os.Getenv("OIDC_ISSUER"), "/"),
+		ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:        strings.Fields(os.Getenv("OIDC_SCOPES")),
+		UsernameClaim: os.Getenv("OIDC_USERNAME_CLAIM"),
+	}
+	if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, errors.New("OIDC_ISSUER, OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required for the oidc auth provider")
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "profile"}
+	}
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = "preferred_username"
+	}
+	return NewOIDCAuthenticator(config), nil
+}
+
+func NewOIDCAuthenticator(config OIDCConfig) *OIDCAuthenticator {
+	return &OIDCAuthenticator{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (a *OIDCAuthenticator) Authenticate(c echo.Context) (*Identity, error) {
+	sess, err := session.Get(AuthSessionKey, c)
+	if err != nil {
+		return nil, ErrNoCredentials
+	}
+	username, ok := sess.Values[sessionUsername].(string)
+	if !ok || username == "" {
+		return nil, ErrNoCredentials
+	}
+	return &Identity{Username: username, Provider: "oidc"}, nil
+}
+
+// LoginHandler は IdP の認可画面にリダイレクトする
+// redirect クエリパラメータでログイン後に戻るパスを指定できる
+func (a *OIDCAuthenticator) LoginHandler(c echo.Context) error {
+	endpoints, err := a.discover()
+	if err != nil {
+		c.Logger().Error("Failed to discover OIDC endpoints:", err)
+		return echo.NewHTTPError(http.StatusBadGateway, "failed to contact identity provider")
+	}
+	sess, err := session.Get(AuthSessionKey, c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get session")
+	}
+	state := randomString()
+	verifier := randomString()
+	sess.Values[sessionState] = state
+	sess.Values[sessionVerifier] = verifier
+	sess.Values[sessionReturnTo] = safeReturnTo(c.QueryParam("redirect"))
+	sess.Options = a.sessionOptions()
+	if err := sess.Save(c.Request(), c.Response()); err != nil {
+		c.Logger().Error("Failed to save session:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to save session")
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {a.config.ClientID},
+		"redirect_uri":          {a.config.RedirectURL},
+		"scope":                 {strings.Join(a.config.Scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	return c.Redirect(http.StatusFound, endpoints.Authorization+"?"+query.Encode())
+}
+
+// CallbackHandler は認可コードをトークンに交換し、userinfo のユーザー名でログインさせる
+func (a *OIDCAuthenticator) CallbackHandler(c echo.Context) error {
+	sess, err := session.Get(AuthSessionKey, c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get session")
+	}
+	state, _ := sess.Values[sessionState].(string)
+	verifier, _ := sess.Values[sessionVerifier].(string)
+	returnTo, _ := sess.Values[sessionReturnTo].(string)
+	delete(sess.Values, sessionState)
+	delete(sess.Values, sessionVerifier)
+	delete(sess.Values, sessionReturnTo)
+
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(c.QueryParam("state"))) != 1 {
+		c.Logger().Warnf("OIDC state mismatch from %s", c.Request().RemoteAddr)
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid state")
+	}
+	if e := c.QueryParam("error"); e != "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "login failed: "+e)
+	}
+	code := c.QueryParam("code")
+	if code == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "missing code")
+	}
+
+	username, err := a.exchange(code, verifier)
+	if err != nil {
+		c.Logger().Error("Failed to complete OIDC login:", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "login failed")
+	}
+	sess.Values[sessionUsername] = username
+	sess.Options = a.sessionOptions()
+	if err := sess.Save(c.Request(), c.Response()); err != nil {
+		c.Logger().Error("Failed to save session:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to save session")
+	}
+	return c.Redirect(http.StatusFound, returnTo)
+}
+
+func (a *OIDCAuthenticator) LogoutHandler(c echo.Context) error {
+	sess, err := session.Get(AuthSessionKey, c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get session")
+	}
+	clear(sess.Values)
+	sess.Options = a.sessionOptions()
+	sess.Options.MaxAge = -1
+	if err := sess.Save(c.Request(), c.Response()); err != nil {
+		c.Logger().Error("Failed to save session:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to save session")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (a *OIDCAuthenticator) sessionOptions() *sessions.Options {
+	return &sessions.Options{
+		Path:     "/",
+		MaxAge:   authSessionMaxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(a.config.RedirectURL, "https://"),
+		// IdP からのリダイレクトでもクッキーが送られるように Lax にする
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// discover は IdP のエンドポイントを取得する。成功した結果だけを覚えておく
+func (a *OIDCAuthenticator) discover() (*oidcEndpoints, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.endpoints != nil {
+		return a.endpoints, nil
+	}
+	res, err := a.client.Get(a.config.Issuer + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery returned %s", res.Status)
+	}
+	var endpoints oidcEndpoints
+	if err := json.NewDecoder(res.Body).Decode(&endpoints); err != nil {
+		return nil, err
+	}
+	if endpoints.Authorization == "" || endpoints.Token == "" || endpoints.UserInfo == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+	a.endpoints = &endpoints
+	return a.endpoints, nil
+}
+
+// exchange は認可コードをアクセストークンに交換し、userinfo からユーザー名を取り出す
+// トークンはバックエンドから IdP に直接取りに行くので、ID トークンの署名は検証せず userinfo を信頼する
+func (a *OIDCAuthenticator) exchange(code, verifier string) (string, error) {
+	endpoints, err := a.discover()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {a.config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoints.Token, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(a.config.ClientID), url.QueryEscape(a.config.ClientSecret))
+	var token struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+	}
+	if err := a.doJSON(req, &token); err != nil {
+		return "", fmt.Errorf("token request: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("token response has no access_token")
+	}
+
+	req, err = http.NewRequest(http.MethodGet, endpoints.UserInfo, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+	var userinfo map[string]any
+	if err := a.doJSON(req, &userinfo); err != nil {
+		return "", fmt.Errorf("userinfo request: %w", err)
+	}
+	username, _ := userinfo[a.config.UsernameClaim].(string)
+	if username == "" {
+		return "", fmt.Errorf("userinfo has no %q claim", a.config.UsernameClaim)
+	}
+	return username, nil
+}
+
+func (a *OIDCAuthenticator) doJSON(req *http.Request, v any) error {
+	res, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("%s: %s", res.Status, body)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// safeReturnTo は別のサイトに飛ばされないよう、同じサイト内のパスだけを戻り先として認める
+func safeReturnTo(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+)
+
+// mockIdP は認可コードフローの token と userinfo だけを実装した IdP
+type mockIdP struct {
+	server *httptest.Server
+
+	mu sync.Mutex
+	// challenges は発行した認可コードごとの code_challenge
+	challenges map[string]string
+}
+
+const (
+	testClientID     = "client"
+	testClientSecret = "client secret"
+	testAccessToken  = "access-token"
+)
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	idp := &mockIdP{challenges: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"userinfo_endpoint":      idp.server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != url.QueryEscape(testClientID) || secret != url.QueryEscape(testClientSecret) {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		idp.mu.Lock()
+		challenge, ok := idp.challenges[r.FormValue("code")]
+		delete(idp.challenges, r.FormValue("code"))
+		idp.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if !ok || r.FormValue("grant_type") != "authorization_code" || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": testAccessToken, "token_type": "Bearer"})
+	})
+	mux.HandleFunc("GET /userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testAccessToken {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"sub": "1234", "preferred_username": "alice"})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// authorize はユーザーが IdP でログインを済ませたものとして、認可コードを発行する
+func (idp *mockIdP) authorize(t *testing.T, authorizeURL string) string {
+	t.Helper()
+	u, err := url.Parse(authorizeURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("client_id") != testClientID || q.Get("code_challenge_method") != "S256" || q.Get("state") == "" {
+		t.Fatalf("unexpected authorization request: %s", authorizeURL)
+	}
+	code := "code-" + q.Get("state")
+	idp.mu.Lock()
+	idp.challenges[code] = q.Get("code_challenge")
+	idp.mu.Unlock()
+	return code
+}
+
+func newOIDCTestServer(t *testing.T, idp *mockIdP) *echo.Echo {
+	t.Helper()
+	a := NewOIDCAuthenticator(OIDCConfig{
+		Issuer:        idp.server.URL,
+		ClientID:      testClientID,
+		ClientSecret:  testClientSecret,
+		RedirectURL:   "http://app.example.com/api/auth/callback",
+		Scopes:        []string{"openid", "profile"},
+		UsernameClaim: "preferred_username",
+	})
+	e := echo.New()
+	e.Use(session.Middleware(sessions.NewCookieStore([]byte(strings.Repeat("k", MinSessionSecretLength)))))
+	e.GET("/api/auth/login", a.LoginHandler)
+	e.GET("/api/auth/callback", a.CallbackHandler)
+	e.POST("/api/auth/logout", a.LogoutHandler)
+	e.GET("/api/me", func(c echo.Context) error {
+		identity, err := a.Authenticate(c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized)
+		}
+		return c.String(http.StatusOK, identity.Username)
+	})
+	return e
+}
+
+// serve はクッキーを付けてリクエストし、返ってきたクッキーで cookies を更新する
+func serve(e *echo.Echo, method, target string, cookies map[string]*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	for _, c := range rec.Result().Cookies() {
+		cookies[c.Name] = c
+	}
+	return rec
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := newMockIdP(t)
+	e := newOIDCTestServer(t, idp)
+
+	tests := []struct {
+		name string
+		// callback は認可コードと state から IdP が返すリダイレクト先のクエリを作る
+		callback   func(code, state string) url.Values
+		wantStatus int
+		wantUser   string
+	}{
+		{
+			name:       "success",
+			callback:   func(code, state string) url.Values { return url.Values{"code": {code}, "state": {state}} },
+			wantStatus: http.StatusFound,
+			wantUser:   "alice",
+		},
+		{
+			name:       "state mismatch",
+			callback:   func(code, state string) url.Values { return url.Values{"code": {code}, "state": {"forged"}} },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "denied by the user",
+			callback:   func(code, state string) url.Values { return url.Values{"error": {"access_denied"}, "state": {state}} },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "missing code",
+			callback:   func(code, state string) url.Values { return url.Values{"state": {state}} },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown code",
+			callback:   func(code, state string) url.Values { return url.Values{"code": {"stolen"}, "state": {state}} },
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cookies := map[string]*http.Cookie{}
+			rec := serve(e, http.MethodGet, "/api/auth/login?redirect=/timeline", cookies)
+			if rec.Code != http.StatusFound {
+				t.Fatalf("login status = %d, want %d", rec.Code, http.StatusFound)
+			}
+			authorizeURL := rec.Header().Get("Location")
+			if !strings.HasPrefix(authorizeURL, idp.server.URL+"/authorize?") {
+				t.Fatalf("login redirected to %q", authorizeURL)
+			}
+			u, _ := url.Parse(authorizeURL)
+			code := idp.authorize(t, authorizeURL)
+
+			rec = serve(e, http.MethodGet, "/api/auth/callback?"+tt.callback(code, u.Query().Get("state")).Encode(), cookies)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("callback status = %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus == http.StatusFound && rec.Header().Get("Location") != "/timeline" {
+				t.Fatalf("callback redirected to %q, want /timeline", rec.Header().Get("Location"))
+			}
+
+			rec = serve(e, http.MethodGet, "/api/me", cookies)
+			if tt.wantUser == "" {
+				if rec.Code != http.StatusUnauthorized {
+					t.Fatalf("authenticated as %q after a failed login", rec.Body)
+				}
+				return
+			}
+			if rec.Code != http.StatusOK || rec.Body.String() != tt.wantUser {
+				t.Fatalf("authenticated as %d %q, want %q", rec.Code, rec.Body, tt.wantUser)
+			}
+
+			// ログアウトしたらセッションは使えない
+			serve(e, http.MethodPost, "/api/auth/logout", cookies)
+			if rec := serve(e, http.MethodGet, "/api/me", cookies); rec.Code != http.StatusUnauthorized {
+				t.Fatalf("still authenticated after logout: %d %q", rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestOIDCRejectsForgedSession(t *testing.T) {
+	idp := newMockIdP(t)
+	e := newOIDCTestServer(t, idp)
+
+	// 別の鍵で署名したセッションは受け付けない
+	forged := sessions.NewCookieStore([]byte("secret"))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	sess, _ := forged.New(req, AuthSessionKey)
+	sess.Values[sessionUsername] = "admin"
+	if err := sess.Save(req, rec); err != nil {
+		t.Fatal(err)
+	}
+	cookies := map[string]*http.Cookie{}
+	for _, c := range rec.Result().Cookies() {
+		cookies[c.Name] = c
+	}
+	if rec := serve(e, http.MethodGet, "/api/me", cookies); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("forged session authenticated as %q", rec.Body)
+	}
+}
+
+func TestSafeReturnTo(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/timeline", "/timeline"},
+		{"", "/"},
+		{"https://evil.example.com", "/"},
+		{"//evil.example.com", "/"},
+		{`/\evil.example.com`, "/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := safeReturnTo(tt.path); got != tt.want {
+				t.Fatalf("safeReturnTo(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// SignatureHeader は認証プロキシが付ける X-Forwarded-User の署名
+	// hex(HMAC-SHA256(FORWARD_AUTH_SECRET, username + "\n" + timestamp))
+	SignatureHeader = "X-Forwarded-User-Signature"
+	// TimestampHeader は署名した時刻 (Unix 秒)
+	TimestampHeader = "X-Forwarded-User-Timestamp"
+
+	// maxSignatureAge は署名を受け付ける時刻のずれの上限
+	maxSignatureAge = 5 * time.Minute
+)
+
+// defaultTrustedProxies は TRUSTED_PROXY_CIDRS が設定されていないときに信頼するネットワーク
+// 同じネットワークの他のコンテナから X-Forwarded-User を偽装されないように、同じホストだけにする
+var defaultTrustedProxies = []string{
+	"127.0.0.0/8",
+	"::1/128",
+}
+
+// ProxyVerifier は X-Forwarded-User が認証プロキシから来たものかを確かめる
+type ProxyVerifier struct {
+	trusted []netip.Prefix
+	secret  []byte
+	now     func() time.Time
+}
+
+// NewProxyVerifierFromEnv は環境変数から ProxyVerifier を作る
+//   - TRUSTED_PROXY_CIDRS: 信頼するプロキシのCIDR (カンマ区切り)。"none" ならどこからも信頼しない。設定しなければループバックだけ
+//   - FORWARD_AUTH_SECRET: 設定すると署名付きのヘッダーはどこから来ても受け付ける
+func NewProxyVerifierFromEnv() (*ProxyVerifier, error) {
+	cidrs := defaultTrustedProxies
+	if s := os.Getenv("TRUSTED_PROXY_CIDRS"); s == "none" {
+		cidrs = nil
+	} else if s != "" {
+		cidrs = strings.Split(s, ",")
+	}
+	return NewProxyVerifier(cidrs, os.Getenv("FORWARD_AUTH_SECRET"))
+}
+
+func NewProxyVerifier(cidrs []string, secret string) (*ProxyVerifier, error) {
+	v := &ProxyVerifier{
+		secret: []byte(secret),
+		now:    time.Now,
+	}
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy CIDR %q: %w", cidr, err)
+		}
+		v.trusted = append(v.trusted, prefix.Masked())
+	}
+	return v, nil
+}
+
+// Verify は username を送ってきたリクエストが信頼できるかを返す
+// 信頼できない場合は理由を返す
+func (v *ProxyVerifier) Verify(r *http.Request, username string) (bool, string) {
+	if r.Header.Get(SignatureHeader) != "" {
+		return v.verifySignature(r, username)
+	}
+	if v.isTrustedPeer(r.RemoteAddr) {
+		return true, ""
+	}
+	return false, "untrusted peer " + r.RemoteAddr
+}
+
+// IPExtractor は信頼するプロキシが付けた X-Forwarded-For だけを使ってクライアントの IP アドレスを決める
+// 信頼しない相手から来たリクエストは直接つながっている相手の IP アドレスになるので、ヘッダーで偽装できない
+func (v *ProxyVerifier) IPExtractor() echo.IPExtractor {
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, prefix := range v.trusted {
+		options = append(options, echo.TrustIPRange(&net.IPNet{
+			IP:   prefix.Addr().AsSlice(),
+			Mask: net.CIDRMask(prefix.Bits(), prefix.Addr().BitLen()),
+		}))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// isTrustedPeer は直接つながっている相手が信頼するプロキシかを返す
+// X-Forwarded-For などは偽装できるので見ない
+func (v *ProxyVerifier) isTrustedPeer(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range v.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *ProxyVerifier) verifySignature(r *http.Request, username string) (bool, string) {
+	if len(v.secret) == 0 {
+		return false, "signed header received but FORWARD_AUTH_SECRET is not set"
+	}
+	timestamp := r.Header.Get(TimestampHeader)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false, "invalid signature timestamp"
+	}
+	age := v.now().Sub(time.Unix(unix, 0))
+	if age > maxSignatureAge || age < -maxSignatureAge {
+		return false, "signature timestamp out of range"
+	}
+	signature, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+	if err != nil {
+		return false, "malformed signature"
+	}
+	if !hmac.Equal(signature, v.sign(username, timestamp)) {
+		return false, "signature mismatch"
+	}
+	return true, ""
+}
+
+func (v *ProxyVerifier) sign(username, timestamp string) []byte {
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(username + "\n" + timestamp))
+	return mac.Sum(nil)
+}
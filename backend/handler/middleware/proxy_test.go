@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestNewProxyVerifierRejectsInvalidCIDR(t *testing.T) {
+	if _, err := NewProxyVerifier([]string{"10.0.0.0/8", "not a cidr"}, ""); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestProxyVerifierVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	signed, err := NewProxyVerifier([]string{"10.0.0.0/8"}, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed.now = func() time.Time { return now }
+	unsigned, err := NewProxyVerifier(defaultTrustedProxies, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(username string, at time.Time) (string, string) {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		return hex.EncodeToString(signed.sign(username, timestamp)), timestamp
+	}
+	validSig, validTS := sign("alice", now)
+	staleSig, staleTS := sign("alice", now.Add(-maxSignatureAge-time.Second))
+
+	tests := []struct {
+		name       string
+		verifier   *ProxyVerifier
+		remoteAddr string
+		username   string
+		signature  string
+		timestamp  string
+		want       bool
+	}{
+		{"trusted peer", signed, "10.1.2.3:1234", "alice", "", "", true},
+		{"untrusted peer", signed, "203.0.113.1:1234", "alice", "", "", false},
+		{"IPv4-mapped IPv6 peer", signed, "[::ffff:10.1.2.3]:1234", "alice", "", "", true},
+		{"loopback by default", unsigned, "127.0.0.1:1234", "alice", "", "", true},
+		{"IPv6 loopback by default", unsigned, "[::1]:1234", "alice", "", "", true},
+		{"private address by default", unsigned, "10.1.2.3:1234", "alice", "", "", false},
+		{"public address by default", unsigned, "198.51.100.1:1234", "alice", "", "", false},
+		{"malformed remote address", signed, "garbage", "alice", "", "", false},
+		{"valid signature from anywhere", signed, "203.0.113.1:1234", "alice", validSig, validTS, true},
+		{"signature for another user", signed, "203.0.113.1:1234", "mallory", validSig, validTS, false},
+		{"stale signature", signed, "203.0.113.1:1234", "alice", staleSig, staleTS, false},
+		{"tampered signature", signed, "203.0.113.1:1234", "alice", "00" + validSig[2:], validTS, false},
+		{"malformed signature", signed, "203.0.113.1:1234", "alice", "zz", validTS, false},
+		{"missing timestamp", signed, "203.0.113.1:1234", "alice", validSig, "", false},
+		// 署名が付いていれば、信頼するネットワークからでも署名で判断する
+		{"bad signature from trusted peer", signed, "10.1.2.3:1234", "alice", "00" + validSig[2:], validTS, false},
+		{"signature without secret", unsigned, "127.0.0.1:1234", "alice", validSig, validTS, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.signature != "" {
+				r.Header.Set(SignatureHeader, tt.signature)
+				r.Header.Set(TimestampHeader, tt.timestamp)
+			}
+			ok, reason := tt.verifier.Verify(r, tt.username)
+			if ok != tt.want {
+				t.Fatalf("Verify() = %v (%s), want %v", ok, reason, tt.want)
+			}
+			if !ok && reason == "" {
+				t.Fatal("Verify() rejected the request without a reason")
+			}
+		})
+	}
+}
+
+func TestNewProxyVerifierFromEnv(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        string
+		remoteAddr string
+		want       bool
+	}{
+		{"unset trusts loopback", "", "127.0.0.1:1234", true},
+		{"unset does not trust private network", "", "10.1.2.3:1234", false},
+		{"unset does not trust private IPv6 network", "", "[fd00::1]:1234", false},
+		{"configured", "10.0.0.0/8, 192.168.0.0/16", "10.1.2.3:1234", true},
+		{"configured replaces loopback", "10.0.0.0/8", "127.0.0.1:1234", false},
+		{"none", "none", "127.0.0.1:1234", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXY_CIDRS", tt.env)
+			t.Setenv("FORWARD_AUTH_SECRET", "")
+			v, err := NewProxyVerifierFromEnv()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.isTrustedPeer(tt.remoteAddr); got != tt.want {
+				t.Fatalf("isTrustedPeer(%q) = %v, want %v", tt.remoteAddr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyVerifierIPExtractor(t *testing.T) {
+	trusted, err := NewProxyVerifier([]string{"10.0.0.0/8", "fd00::/8"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	none, err := NewProxyVerifier(nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		verifier   *ProxyVerifier
+		remoteAddr string
+		xff        string
+		want       string
+	}{
+		{"direct client", trusted, "203.0.113.1:1234", "", "203.0.113.1"},
+		{"spoofed header from untrusted peer", trusted, "203.0.113.1:1234", "198.51.100.7", "203.0.113.1"},
+		{"through trusted proxy", trusted, "10.0.0.2:1234", "198.51.100.7", "198.51.100.7"},
+		{"through trusted IPv6 proxy", trusted, "[fd00::2]:1234", "198.51.100.7", "198.51.100.7"},
+		// クライアントが先頭に付けた値は使わず、信頼するプロキシが付けた最後の値を使う
+		{"spoofed prefix through trusted proxy", trusted, "10.0.0.2:1234", "192.0.2.9, 198.51.100.7", "198.51.100.7"},
+		{"chain of trusted proxies", trusted, "10.0.0.2:1234", "198.51.100.7, 10.0.0.3", "198.51.100.7"},
+		// プライベートネットワークやループバックも設定しなければ信頼しない
+		{"loopback is not trusted unless configured", trusted, "127.0.0.1:1234", "198.51.100.7", "127.0.0.1"},
+		{"none", none, "10.0.0.2:1234", "198.51.100.7", "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				req.Header.Set(echo.HeaderXForwardedFor, tt.xff)
+			}
+			if got := tt.verifier.IPExtractor()(req); got != tt.want {
+				t.Fatalf("IPExtractor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
+)
+
+// レート制限のグループ。グループごとに RATE_LIMITS で制限を変えられる
+const (
+	// RateLimitDefault は /api 以下のすべてのリクエスト
+	RateLimitDefault = "default"
+	// RateLimitPost はメッセージの投稿
+	RateLimitPost = "post"
+	// RateLimitReact はリアクション・リポスト・ブックマークなど、付けたり外したりできる操作
+	RateLimitReact = "react"
+)
+
+// RateLimit はトークンバケットの設定。Per の間に Requests 回までリクエストでき、連続では Requests 回まで使える
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// RateLimits はグループごとの制限
+type RateLimits map[string]RateLimit
+
+// DefaultRateLimits は RATE_LIMITS で上書きされなかったグループの制限
+var DefaultRateLimits = RateLimits{
+	RateLimitDefault: {Requests: 300, Per: time.Minute},
+	RateLimitPost:    {Requests: 10, Per: time.Minute},
+	RateLimitReact:   {Requests: 60, Per: time.Minute},
+}
+
+// NewRateLimitsFromEnv は RATE_LIMITS (例: "post=5/1m,react=30/1m") で DefaultRateLimits を上書きする
+func NewRateLimitsFromEnv() (RateLimits, error) {
+	limits := RateLimits{}
+	for group, limit := range DefaultRateLimits {
+		limits[group] = limit
+	}
+	for _, entry := range strings.Split(os.Getenv("RATE_LIMITS"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		group, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid RATE_LIMITS entry %q: want group=requests/duration", entry)
+		}
+		requests, per, ok := strings.Cut(value, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid RATE_LIMITS entry %q: want group=requests/duration", entry)
+		}
+		n, err := strconv.Atoi(requests)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid RATE_LIMITS entry %q: requests must be a positive integer", entry)
+		}
+		d, err := time.ParseDuration(per)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid RATE_LIMITS entry %q: duration must be positive", entry)
+		}
+		group = strings.TrimSpace(group)
+		if _, ok := DefaultRateLimits[group]; !ok {
+			return nil, fmt.Errorf("invalid RATE_LIMITS entry %q: unknown group %q", entry, group)
+		}
+		limits[group] = RateLimit{Requests: n, Per: d}
+	}
+	return limits, nil
+}
+
+// RateLimitResult は1回分のトークンを取ろうとした結果
+type RateLimitResult struct {
+	Allowed bool
+	// Remaining は残りのトークン数
+	Remaining int
+	// RetryAfter は次のトークンが貯まるまでの時間。Allowed のときは 0
+	RetryAfter time.Duration
+	// ResetAfter はバケットが満タンに戻るまでの時間
+	ResetAfter time.Duration
+}
+
+// RateLimitStore はキーごとのトークンバケットを持つ
+// 複数のサーバーで制限を共有する場合は Redis などを使って実装する
+type RateLimitStore interface {
+	Take(key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	// full はこの時刻を過ぎるとバケットが満タンになり、捨てても同じになる
+	full time.Time
+}
+
+// MemoryRateLimitStore はプロセス内にバケットを持つ RateLimitStore
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]*tokenBucket{}}
+}
+
+// sweepInterval ごとに満タンに戻ったバケットを捨てる
+const sweepInterval = time.Minute
+
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for k, b := range s.buckets {
+			if now.After(b.full) {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	burst := float64(limit.Requests)
+	rate := burst / limit.Per.Seconds() // 1秒あたりに貯まるトークン
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: burst, last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	result := RateLimitResult{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = time.Duration((burst - b.tokens) / rate * float64(time.Second))
+	b.full = now.Add(result.ResetAfter)
+	return result, nil
+}
+
+type RateLimiterConfig struct {
+	Skipper echomw.Skipper
+	Store   RateLimitStore
+	// Group はキーに含めるグループ名。同じルートに複数の制限をかけても別々に数えられる
+	Group string
+	Limit RateLimit
+}
+
+// RateLimiter はユーザーとルートごとにトークンバケットで制限し、超えたら 429 を返す
+// UsernameProvider の後に使う。ユーザー名がないリクエスト(ログインの画面など)は IP アドレスで数える
+// IP アドレスは c.RealIP() で取るので、偽装されないように e.IPExtractor を設定しておく
+// X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset (満タンに戻るまでの秒数) を付ける
+func RateLimiter(config RateLimiterConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = echomw.DefaultSkipper
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+			client, ok := c.Get(UsernameKey).(string)
+			if !ok {
+				client = "ip:" + c.RealIP()
+			}
+			key := config.Group + "\x00" + client + "\x00" + c.Request().Method + " " + c.Path()
+
+			result, err := config.Store.Take(key, config.Limit, time.Now())
+			if err != nil {
+				// ストアが使えなくてもサービスは止めない
+				c.Logger().Warnf("Rate limit store failed: %v", err)
+				return next(c)
+			}
+			header := c.Response().Header()
+			header.Set("X-RateLimit-Limit", strconv.Itoa(config.Limit.Requests))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("X-RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.ResetAfter), 10))
+			if !result.Allowed {
+				header.Set("Retry-After", strconv.FormatInt(ceilSeconds(result.RetryAfter), 10))
+				return echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded")
+			}
+			return next(c)
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestMemoryRateLimitStoreTake(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	limit := RateLimit{Requests: 2, Per: 4 * time.Second} // 2秒に1つ貯まる
+
+	tests := []struct {
+		name       string
+		key        string
+		at         time.Duration // start からの時間
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+		resetAfter time.Duration
+	}{
+		{"first request", "alice", 0, true, 1, 0, 2 * time.Second},
+		{"burst", "alice", 0, true, 0, 0, 4 * time.Second},
+		{"exhausted", "alice", 0, false, 0, 2 * time.Second, 4 * time.Second},
+		{"partly refilled", "alice", time.Second, false, 0, time.Second, 3 * time.Second},
+		{"other key", "bob", time.Second, true, 1, 0, 2 * time.Second},
+		{"refilled one token", "alice", 2 * time.Second, true, 0, 0, 4 * time.Second},
+		{"refilled up to burst", "alice", time.Minute, true, 1, 0, 2 * time.Second},
+	}
+	store := NewMemoryRateLimitStore()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Take(tt.key, limit, start.Add(tt.at))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := RateLimitResult{Allowed: tt.allowed, Remaining: tt.remaining, RetryAfter: tt.retryAfter, ResetAfter: tt.resetAfter}
+			if got != want {
+				t.Fatalf("Take() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestMemoryRateLimitStoreSweep(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	limit := RateLimit{Requests: 10, Per: time.Minute}
+	store := NewMemoryRateLimitStore()
+	for _, key := range []string{"alice", "bob"} {
+		if _, err := store.Take(key, limit, start); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// bob はまだ満タンに戻っていないので残す
+	if _, err := store.Take("bob", limit, start.Add(sweepInterval-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Take("carol", limit, start.Add(sweepInterval+time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.buckets["alice"]; ok {
+		t.Error("full bucket for alice was not swept")
+	}
+	if _, ok := store.buckets["bob"]; !ok {
+		t.Error("bucket for bob was swept before it was full")
+	}
+	if _, ok := store.buckets["carol"]; !ok {
+		t.Error("bucket for carol was not created")
+	}
+}
+
+func TestNewRateLimitsFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		want    RateLimits
+		wantErr bool
+	}{
+		{"unset", "", DefaultRateLimits, false},
+		{"override", " post=5/1m , react=30/30s", RateLimits{
+			RateLimitDefault: DefaultRateLimits[RateLimitDefault],
+			RateLimitPost:    {Requests: 5, Per: time.Minute},
+			RateLimitReact:   {Requests: 30, Per: 30 * time.Second},
+		}, false},
+		{"missing equals", "post", nil, true},
+		{"missing duration", "post=5", nil, true},
+		{"zero requests", "post=0/1m", nil, true},
+		{"invalid duration", "post=5/minute", nil, true},
+		{"negative duration", "post=5/-1m", nil, true},
+		{"unknown group", "upload=5/1m", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("RATE_LIMITS", tt.env)
+			got, err := NewRateLimitsFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRateLimitsFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("NewRateLimitsFromEnv() = %v, want %v", got, tt.want)
+			}
+			for group, limit := range tt.want {
+				if got[group] != limit {
+					t.Fatalf("NewRateLimitsFromEnv()[%q] = %v, want %v", group, got[group], limit)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterKeysAnonymousRequestsOnPeerAddress(t *testing.T) {
+	verifier, err := NewProxyVerifier([]string{"10.0.0.0/8"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	e.IPExtractor = verifier.IPExtractor()
+	e.Use(RateLimiter(RateLimiterConfig{
+		Store: NewMemoryRateLimitStore(),
+		Group: RateLimitDefault,
+		Limit: RateLimit{Requests: 1, Per: time.Hour},
+	}))
+	e.GET("/", func(c echo.Context) error { return c.NoContent(http.StatusNoContent) })
+
+	do := func(remoteAddr, xff string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		if xff != "" {
+			req.Header.Set(echo.HeaderXForwardedFor, xff)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := do("203.0.113.1:1234", ""); code != http.StatusNoContent {
+		t.Fatalf("first request: status %d", code)
+	}
+	// 信頼しない相手が X-Forwarded-For を変えても別のクライアントにはならない
+	if code := do("203.0.113.1:1234", "198.51.100.7"); code != http.StatusTooManyRequests {
+		t.Fatalf("spoofed X-Forwarded-For: status %d, want 429", code)
+	}
+	// 信頼するプロキシを通ったクライアントはそれぞれ数える
+	if code := do("10.0.0.2:1234", "198.51.100.7"); code != http.StatusNoContent {
+		t.Fatalf("client behind proxy: status %d", code)
+	}
+	if code := do("10.0.0.2:1234", "198.51.100.8"); code != http.StatusNoContent {
+		t.Fatalf("another client behind proxy: status %d", code)
+	}
+	if code := do("10.0.0.3:1234", "198.51.100.7"); code != http.StatusTooManyRequests {
+		t.Fatalf("same client through another proxy: status %d, want 429", code)
+	}
+}
@@ -1,25 +1,55 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"os"
 
 	"github.com/labstack/echo/v4"
+	echomw "github.com/labstack/echo/v4/middleware"
 )
 
 const UsernameKey = "username"
+const IdentityKey = "identity"
 const DevelopmentEnv = "development"
 
-func UsernameProvider(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		username := c.Request().Header.Get("X-Forwarded-User")
-		if username != "" {
-			c.Set(UsernameKey, username)
-		} else if os.Getenv("ENVIRONMENT") == DevelopmentEnv {
-			c.Set(UsernameKey, "anonymous")
-		} else {
+type UsernameProviderConfig struct {
+	// Skipper が true を返したリクエストは認証しない (ログインの画面など)
+	Skipper echomw.Skipper
+	// Authenticators は前から順に試される。最初に認証情報を見つけたものの結果を使う
+	Authenticators []Authenticator
+}
+
+// UsernameProvider はリクエストを認証し、ユーザー名を UsernameKey に、認証の詳細を IdentityKey に入れる
+// どの Authenticator でも認証情報が見つからなければ、開発環境では "anonymous" として扱い、それ以外では 401 を返す
+func UsernameProvider(config UsernameProviderConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = echomw.DefaultSkipper
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+			for _, authenticator := range config.Authenticators {
+				identity, err := authenticator.Authenticate(c)
+				if errors.Is(err, ErrNoCredentials) {
+					continue
+				}
+				if err != nil {
+					c.Logger().Warnf("Rejected credentials from %s: %v", c.Request().RemoteAddr, err)
+					return echo.NewHTTPError(http.StatusUnauthorized)
+				}
+				c.Set(UsernameKey, identity.Username)
+				c.Set(IdentityKey, identity)
+				return next(c)
+			}
+			if os.Getenv("ENVIRONMENT") == DevelopmentEnv {
+				c.Set(UsernameKey, "anonymous")
+				c.Set(IdentityKey, &Identity{Username: "anonymous", Provider: "development"})
+				return next(c)
+			}
 			return echo.NewHTTPError(http.StatusUnauthorized)
 		}
-		return next(c)
 	}
 }
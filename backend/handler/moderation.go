@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+// recordModeration は操作の記録を残す。操作自体は済んでいるので失敗してもログに出すだけにする
+func (h *handler) recordModeration(ctx echo.Context, log domain.ModerationLog) {
+	log.Moderator = ctx.Get(m.UsernameKey).(string)
+	if err := h.repo.CreateModerationLog(log); err != nil {
+		ctx.Logger().Error("Failed to record moderation log:", err)
+	}
+}
+
+// setMessageStatusAsModerator はメッセージの状態を変えて記録する
+func (h *handler) setMessageStatusAsModerator(ctx echo.Context, ID uuid.UUID, from, to domain.MessageStatus, action domain.ModerationAction) error {
+	msg, err := h.repo.GetMessageByID(ID)
+	if err != nil {
+		return err
+	}
+	if err := h.repo.SetMessageStatus(ID, from, to); err != nil {
+		return err
+	}
+	h.recordModeration(ctx, domain.ModerationLog{
+		Action:     action,
+		MessageID:  ID,
+		TargetUser: msg.Author,
+	})
+	return nil
+}
+
+func (h *handler) HideMessageHandler(ctx echo.Context) error {
+	ID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
+	}
+	if err := h.setMessageStatusAsModerator(ctx, ID, domain.MessageStatusPublished, domain.MessageStatusHidden, domain.ModerationActionHide); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "published message not found")
+		}
+		ctx.Logger().Error("Failed to hide message:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to hide message")
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (h *handler) UnhideMessageHandler(ctx echo.Context) error {
+	ID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
+	}
+	if err := h.setMessageStatusAsModerator(ctx, ID, domain.MessageStatusHidden, domain.MessageStatusPublished, domain.ModerationActionUnhide); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "hidden message not found")
+		}
+		ctx.Logger().Error("Failed to unhide message:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to unhide message")
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (h *handler) RemoveReactionHandler(ctx echo.Context) error {
+	ID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
+	}
+	if err := h.repo.DeleteMessageReaction(ID, ctx.Param("username")); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "reaction not found")
+		}
+		ctx.Logger().Error("Failed to remove reaction:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to remove reaction")
+	}
+	h.recordModeration(ctx, domain.ModerationLog{
+		Action:     domain.ModerationActionRemoveReaction,
+		MessageID:  ID,
+		TargetUser: ctx.Param("username"),
+	})
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (h *handler) RevokeAchievementHandler(ctx echo.Context) error {
+	if err := h.repo.DeleteUserAchievement(ctx.Param("name"), ctx.Param("achievement")); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "achievement not found")
+		}
+		ctx.Logger().Error("Failed to revoke achievement:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to revoke achievement")
+	}
+	h.recordModeration(ctx, domain.ModerationLog{
+		Action:     domain.ModerationActionRevokeAchievement,
+		TargetUser: ctx.Param("name"),
+		Note:       ctx.Param("achievement"),
+	})
+	return ctx.NoContent(http.StatusNoContent)
+}
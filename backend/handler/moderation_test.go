@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+// achievementRepo はユーザーごとの実績と、記録されたモデレーションの操作を持つ
+type achievementRepo struct {
+	fakeRepo
+	achievements map[string][]string
+	deleteErr    error
+	logs         []domain.ModerationLog
+}
+
+func (r *achievementRepo) DeleteUserAchievement(username string, achievementName string) error {
+	if r.deleteErr != nil {
+		return r.deleteErr
+	}
+	for i, name := range r.achievements[username] {
+		if name == achievementName {
+			r.achievements[username] = append(r.achievements[username][:i], r.achievements[username][i+1:]...)
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
+func (r *achievementRepo) CreateModerationLog(log domain.ModerationLog) error {
+	r.logs = append(r.logs, log)
+	return nil
+}
+
+func TestRevokeAchievementHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		achievement string
+		deleteErr   error
+		want        int
+	}{
+		{"revoked", "ぐるぐる", nil, http.StatusNoContent},
+		{"not achieved", "読込中", nil, http.StatusNotFound},
+		{"repository error", "ぐるぐる", errors.New("db down"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &achievementRepo{
+				achievements: map[string][]string{"alice": {"ぐるぐる"}},
+				deleteErr:    tt.deleteErr,
+			}
+			h := newTestHandler(t, repo)
+			c, rec := newTestContext(http.MethodDelete, "/", "mod", "name", "alice", "achievement", tt.achievement)
+			err := h.RevokeAchievementHandler(c)
+			code := rec.Code
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				code = httpErr.Code
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.want {
+				t.Fatalf("status = %d, want %d", code, tt.want)
+			}
+
+			if tt.want != http.StatusNoContent {
+				if len(repo.logs) != 0 {
+					t.Fatalf("moderation logs = %v, want none", repo.logs)
+				}
+				return
+			}
+			if len(repo.achievements["alice"]) != 0 {
+				t.Fatalf("achievements = %v, want none", repo.achievements["alice"])
+			}
+			want := domain.ModerationLog{Moderator: "mod", Action: domain.ModerationActionRevokeAchievement, TargetUser: "alice", Note: tt.achievement}
+			if len(repo.logs) != 1 || repo.logs[0] != want {
+				t.Fatalf("moderation logs = %+v, want %+v", repo.logs, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+// mutedUser はミュートまたはブロックしたユーザー
+type mutedUser struct {
+	TraqID    string    `json:"traqId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (h *handler) GetMyMutesHandler(ctx echo.Context) error {
+	username := ctx.Get(m.UsernameKey).(string)
+	limit, offset, err := parseMuteListParams(ctx)
+	if err != nil {
+		return err
+	}
+	mutes, err := h.repo.GetMutes(username, limit, offset)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve mutes:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve mutes")
+	}
+	result := make([]mutedUser, len(mutes))
+	for i, mute := range mutes {
+		result[i] = mutedUser{TraqID: mute.Mutee, CreatedAt: mute.CreatedAt}
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+func (h *handler) MuteUserHandler(ctx echo.Context) error {
+	return h.addRelation(ctx, h.repo.InsertMute, "mute", "muted")
+}
+
+func (h *handler) UnmuteUserHandler(ctx echo.Context) error {
+	return h.removeRelation(ctx, h.repo.DeleteMute, "mute", "muted")
+}
+
+func (h *handler) GetMyBlocksHandler(ctx echo.Context) error {
+	username := ctx.Get(m.UsernameKey).(string)
+	limit, offset, err := parseMuteListParams(ctx)
+	if err != nil {
+		return err
+	}
+	blocks, err := h.repo.GetBlocks(username, limit, offset)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve blocks:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve blocks")
+	}
+	result := make([]mutedUser, len(blocks))
+	for i, block := range blocks {
+		result[i] = mutedUser{TraqID: block.Blocked, CreatedAt: block.CreatedAt}
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+func (h *handler) BlockUserHandler(ctx echo.Context) error {
+	return h.addRelation(ctx, h.repo.InsertBlock, "block", "blocked")
+}
+
+func (h *handler) UnblockUserHandler(ctx echo.Context) error {
+	return h.removeRelation(ctx, h.repo.DeleteBlock, "block", "blocked")
+}
+
+func parseMuteListParams(ctx echo.Context) (int64, int64, error) {
+	limit, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("limit"), "50"), 10, 64)
+	if err != nil || limit <= 0 || limit > 100 {
+		return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid limit parameter")
+	}
+	offset, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("offset"), "0"), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid offset parameter")
+	}
+	return limit, offset, nil
+}
+
+// addRelation はリクエストボディの traqId のユーザーをミュートまたはブロックする
+func (h *handler) addRelation(ctx echo.Context, insert func(from, to string) error, verb, past string) error {
+	username := ctx.Get(m.UsernameKey).(string)
+	var reqBody struct {
+		TraqID string `json:"traqId"`
+	}
+	if err := ctx.Bind(&reqBody); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	if reqBody.TraqID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "traqId is required")
+	}
+	if !domain.IsValidUsername(reqBody.TraqID) {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("traqId must be 1 to %d letters, digits, _ or -", domain.MaxUsernameLength))
+	}
+	if reqBody.TraqID == username {
+		return echo.NewHTTPError(http.StatusBadRequest, "cannot "+verb+" yourself")
+	}
+	if err := insert(username, reqBody.TraqID); err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			return echo.NewHTTPError(http.StatusConflict, "already "+past)
+		}
+		ctx.Logger().Error("Failed to "+verb+" user:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to "+verb+" user")
+	}
+	return ctx.JSON(http.StatusCreated, mutedUser{TraqID: reqBody.TraqID, CreatedAt: time.Now()})
+}
+
+func (h *handler) removeRelation(ctx echo.Context, remove func(from, to string) error, verb, past string) error {
+	username := ctx.Get(m.UsernameKey).(string)
+	target := ctx.Param("name")
+	if target == "" {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	if err := remove(username, target); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "not "+past)
+		}
+		ctx.Logger().Error("Failed to un"+verb+" user:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to un"+verb+" user")
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// relationRepo はミュートしたユーザーを記録する
+type relationRepo struct {
+	fakeRepo
+	muted []string
+}
+
+func (r *relationRepo) InsertMute(muter, mutee string) error {
+	r.muted = append(r.muted, mutee)
+	return nil
+}
+
+func TestAddRelationValidatesTraqID(t *testing.T) {
+	tests := []struct {
+		name   string
+		traqID string
+		want   int
+	}{
+		{"valid", "bob_2-x", http.StatusCreated},
+		{"at the length limit", strings.Repeat("a", 32), http.StatusCreated},
+		{"empty", "", http.StatusBadRequest},
+		{"too long", strings.Repeat("a", 33), http.StatusBadRequest},
+		{"space", "bob smith", http.StatusBadRequest},
+		{"multibyte", "ぼぶ", http.StatusBadRequest},
+		{"self", "alice", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &relationRepo{}
+			h := newTestHandler(t, repo)
+			c, rec := newTestContext(http.MethodPost, "/", "alice")
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"traqId":"`+tt.traqID+`"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			c.SetRequest(req)
+
+			err := h.MuteUserHandler(c)
+			code := rec.Code
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				code = httpErr.Code
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.want {
+				t.Fatalf("status = %d, want %d", code, tt.want)
+			}
+			if wrote := len(repo.muted) > 0; wrote != (tt.want == http.StatusCreated) {
+				t.Fatalf("muted = %v", repo.muted)
+			}
+		})
+	}
+}
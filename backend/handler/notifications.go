@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"cmp"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+type notification struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	Actor     string    `json:"actor"`
+	MessageID uuid.UUID `json:"messageId"`
+	Read      bool      `json:"read"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// notify は username に通知を作成する。自分自身の操作と username がブロックしているユーザーの操作は通知しない
+// 通知の作成に失敗しても元の操作は成功させたいので、エラーはログに出すだけにする
+func (h *handler) notify(logger echo.Logger, username string, notificationType domain.NotificationType, actor string, messageID uuid.UUID) {
+	if username == actor {
+		return
+	}
+	blocked, err := h.repo.IsBlocking(username, actor)
+	if err != nil {
+		logger.Error("Failed to retrieve block state:", username, actor, err)
+		return
+	}
+	if blocked {
+		return
+	}
+	if _, err := h.repo.CreateNotification(username, notificationType, actor, messageID); err != nil {
+		logger.Error("Failed to create notification:", username, notificationType, messageID, err)
+	}
+}
+
+// notifyNewMessage は公開されたメッセージの返信先の作者とメンションされたユーザーに通知する
+// 返信でなければ parentAuthor は空文字列
+func (h *handler) notifyNewMessage(logger echo.Logger, msg *domain.Message, parentAuthor string) {
+	if parentAuthor != "" {
+		h.notify(logger, parentAuthor, domain.NotificationTypeReply, msg.Author, msg.ID)
+	}
+	notified := map[string]bool{parentAuthor: true}
+	for _, mt := range domain.ExtractMentions(msg.Content) {
+		if notified[mt.Username] {
+			continue
+		}
+		notified[mt.Username] = true
+		h.notify(logger, mt.Username, domain.NotificationTypeMention, msg.Author, msg.ID)
+	}
+}
+
+func (h *handler) GetMyNotificationsHandler(ctx echo.Context) error {
+	username := ctx.Get(m.UsernameKey).(string)
+	limit, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("limit"), "20"), 10, 64)
+	if err != nil || limit <= 0 || limit > 100 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit parameter")
+	}
+	offset, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("offset"), "0"), 10, 64)
+	if err != nil || offset < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offset parameter")
+	}
+	unreadOnly := ctx.QueryParam("unread") == "true"
+
+	notifications, err := h.repo.GetNotifications(username, limit, offset, unreadOnly)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve notifications:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve notifications")
+	}
+	result := make([]notification, len(notifications))
+	for i, n := range notifications {
+		result[i] = notification{
+			ID:        n.ID,
+			Type:      string(n.Type),
+			Actor:     n.Actor,
+			MessageID: n.MessageID,
+			Read:      n.Read,
+			CreatedAt: n.CreatedAt,
+		}
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+func (h *handler) GetMyUnreadNotificationCountHandler(ctx echo.Context) error {
+	username := ctx.Get(m.UsernameKey).(string)
+	count, err := h.repo.CountUnreadNotifications(username)
+	if err != nil {
+		ctx.Logger().Error("Failed to count unread notifications:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to count notifications")
+	}
+	return ctx.JSON(http.StatusOK, map[string]int64{"count": count})
+}
+
+func (h *handler) ReadNotificationHandler(ctx echo.Context) error {
+	username := ctx.Get(m.UsernameKey).(string)
+	ID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
+	}
+	if err := h.repo.MarkNotificationRead(username, ID); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "notification not found")
+		}
+		ctx.Logger().Error("Failed to mark notification as read:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update notification")
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (h *handler) ReadAllNotificationsHandler(ctx echo.Context) error {
+	username := ctx.Get(m.UsernameKey).(string)
+	if _, err := h.repo.MarkAllNotificationsRead(username); err != nil {
+		ctx.Logger().Error("Failed to mark notifications as read:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update notifications")
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
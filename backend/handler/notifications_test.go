@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+// notificationRepo はブロックの関係を持ち、作られた通知の宛先を記録する
+type notificationRepo struct {
+	fakeRepo
+	blocks   map[[2]string]bool
+	blockErr error
+	notified []string
+}
+
+func (r *notificationRepo) IsBlocking(blocker, blocked string) (bool, error) {
+	if r.blockErr != nil {
+		return false, r.blockErr
+	}
+	return r.blocks[[2]string{blocker, blocked}], nil
+}
+
+func (r *notificationRepo) CreateNotification(username string, notificationType domain.NotificationType, actor string, messageID uuid.UUID) (*domain.Notification, error) {
+	r.notified = append(r.notified, username)
+	return &domain.Notification{Username: username, Type: notificationType, Actor: actor, MessageID: messageID}, nil
+}
+
+func TestNotify(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		actor    string
+		blocks   map[[2]string]bool
+		blockErr error
+		want     bool
+	}{
+		{"other user", "alice", "bob", nil, nil, true},
+		{"self", "alice", "alice", nil, nil, false},
+		{"recipient blocks actor", "alice", "bob", map[[2]string]bool{{"alice", "bob"}: true}, nil, false},
+		{"actor blocks recipient", "alice", "bob", map[[2]string]bool{{"bob", "alice"}: true}, nil, true},
+		{"block state unavailable", "alice", "bob", nil, errors.New("db down"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &notificationRepo{blocks: tt.blocks, blockErr: tt.blockErr}
+			h := newTestHandler(t, repo)
+			h.notify(echo.New().Logger, tt.username, domain.NotificationTypeReaction, tt.actor, uuid.New())
+			if got := len(repo.notified) == 1; got != tt.want {
+				t.Fatalf("notified = %v, want notification: %v", repo.notified, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+func (h *handler) PinMessageHandler(c echo.Context) error {
+	ID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
+	}
+	msg, err := h.repo.GetMessageByID(ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "id not found")
+		}
+		c.Logger().Error("Failed to retrieve message:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve message")
+	}
+	if msg.Status != domain.MessageStatusPublished {
+		return echo.NewHTTPError(http.StatusNotFound, "id not found")
+	}
+	username := c.Get(m.UsernameKey).(string)
+	if msg.Author != username {
+		return echo.NewHTTPError(http.StatusForbidden, "cannot pin other user's message")
+	}
+	if err := h.repo.PinMessage(ID, username); err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			return echo.NewHTTPError(http.StatusConflict, "already pinned")
+		}
+		if errors.Is(err, domain.ErrLimitExceeded) {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("cannot pin more than %d messages", domain.MaxPinnedMessages))
+		}
+		c.Logger().Error("Failed to pin message:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to pin message")
+	}
+	return c.JSON(http.StatusCreated, map[string]bool{"pinned": true})
+}
+
+func (h *handler) UnpinMessageHandler(c echo.Context) error {
+	ID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
+	}
+	username := c.Get(m.UsernameKey).(string)
+	// 固定は作者しかできないので、username で絞れば他人のメッセージは見つからない扱いになる
+	if err := h.repo.UnpinMessage(ID, username); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "pinned message not found")
+		}
+		c.Logger().Error("Failed to unpin message:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to unpin message")
+	}
+	return c.JSON(http.StatusOK, map[string]bool{"pinned": false})
+}
+
+func (h *handler) GetUserPinnedMessagesHandler(ctx echo.Context) error {
+	username := ctx.Param("name")
+	messages, err := h.repo.GetPinnedMessages(username, ctx.Get(m.UsernameKey).(string))
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve pinned messages:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve pinned messages")
+	}
+	result, err := h.buildMessages(ctx, messages)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+type pollOption struct {
+	Index int    `json:"index"`
+	Label string `json:"label"`
+	Votes int64  `json:"votes"`
+}
+
+type poll struct {
+	Options   []pollOption `json:"options"`
+	Multiple  bool         `json:"multiple"`
+	ExpiresAt time.Time    `json:"expiresAt"`
+	Expired   bool         `json:"expired"`
+	Voters    int64        `json:"voters"`
+	// MyVotes は自分が選んだ選択肢の index。投票していなければ空
+	MyVotes []int `json:"myVotes"`
+}
+
+func toPoll(p *domain.Poll, myVotes []int) *poll {
+	options := make([]pollOption, len(p.Options))
+	for i, o := range p.Options {
+		options[i] = pollOption{
+			Index: o.Index,
+			Label: o.Label,
+			Votes: o.Votes,
+		}
+	}
+	return &poll{
+		Options:   options,
+		Multiple:  p.Multiple,
+		ExpiresAt: p.ExpiresAt,
+		Expired:   p.Expired(time.Now()),
+		Voters:    p.Voters,
+		MyVotes:   myVotes,
+	}
+}
+
+// getPoll はメッセージに付いた投票を返す。投票が付いていなければ nil を返す
+func (h *handler) getPoll(ctx echo.Context, messageID uuid.UUID) (*poll, error) {
+	p, err := h.repo.GetPoll(messageID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, nil
+		}
+		ctx.Logger().Error("Failed to retrieve poll for message:", messageID, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	myVotes, err := h.repo.GetMyPollVotes(messageID, ctx.Get(m.UsernameKey).(string))
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve poll votes for message:", messageID, err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	return toPoll(p, myVotes), nil
+}
+
+// parsePollForm は投稿フォームの pollOptions, pollMultiple, pollExpiresAt を読む
+// 投票が指定されていなければ nil を返す。投票は公開されてから受け付けるので期限は publishAt から数える
+func parsePollForm(c echo.Context, publishAt time.Time) (*domain.NewPoll, error) {
+	form, err := c.FormParams()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid form")
+	}
+	options := form["pollOptions"]
+	if len(options) == 0 {
+		return nil, nil
+	}
+	if len(options) < domain.MinPollOptions || len(options) > domain.MaxPollOptions {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Poll must have %d to %d options", domain.MinPollOptions, domain.MaxPollOptions))
+	}
+	for i, option := range options {
+		option = strings.TrimSpace(option)
+		if option == "" || utf8.RuneCountInString(option) > domain.MaxPollOptionLength {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Poll option must be 1 to %d characters", domain.MaxPollOptionLength))
+		}
+		if slices.Contains(options[:i], option) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "Poll options must be unique")
+		}
+		options[i] = option
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, c.FormValue("pollExpiresAt"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid pollExpiresAt")
+	}
+	opensAt := time.Now()
+	if !publishAt.IsZero() {
+		opensAt = publishAt
+	}
+	if !expiresAt.After(opensAt) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "pollExpiresAt must be after the message is published")
+	}
+	if expiresAt.After(opensAt.Add(domain.MaxPollDuration)) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "pollExpiresAt is too far in the future")
+	}
+
+	return &domain.NewPoll{
+		Options:   options,
+		Multiple:  c.FormValue("pollMultiple") == "true",
+		ExpiresAt: expiresAt.UTC().Truncate(time.Second),
+	}, nil
+}
+
+func (h *handler) VotePollHandler(c echo.Context) error {
+	ID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
+	}
+	var reqBody struct {
+		Choices []int `json:"choices"`
+	}
+	if err := c.Bind(&reqBody); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	msg, err := h.repo.GetMessageByID(ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "id not found")
+		}
+		c.Logger().Error("Failed to retrieve message:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve message")
+	}
+	if msg.Status != domain.MessageStatusPublished {
+		return echo.NewHTTPError(http.StatusNotFound, "id not found")
+	}
+	p, err := h.repo.GetPoll(ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "poll not found")
+		}
+		c.Logger().Error("Failed to retrieve poll:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve poll")
+	}
+	if p.Expired(time.Now()) {
+		return echo.NewHTTPError(http.StatusConflict, "poll has expired")
+	}
+
+	choices := slices.Clone(reqBody.Choices)
+	slices.Sort(choices)
+	choices = slices.Compact(choices)
+	if len(choices) == 0 || len(choices) != len(reqBody.Choices) {
+		return echo.NewHTTPError(http.StatusBadRequest, "choices must be unique and not empty")
+	}
+	if !p.Multiple && len(choices) != 1 {
+		return echo.NewHTTPError(http.StatusBadRequest, "only one choice is allowed")
+	}
+	for _, choice := range choices {
+		if choice < 0 || choice >= len(p.Options) {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid choice")
+		}
+	}
+
+	username := c.Get(m.UsernameKey).(string)
+	if err := h.repo.VotePoll(ID, username, choices); err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			return echo.NewHTTPError(http.StatusConflict, "already voted")
+		}
+		c.Logger().Error("Failed to vote:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to vote")
+	}
+
+	result, err := h.getPoll(c, ID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, result)
+}
@@ -16,7 +16,7 @@ func (h *handler) ReactionsAdder(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
 	}
 	// idのメッセージがそもそも存在するか
-	_, err = h.repo.GetMessageByID(ID)
+	msg, err := h.repo.GetMessageByID(ID)
 	if err != nil {
 		if errors.Is(err, domain.ErrNotFound) {
 			return echo.NewHTTPError(http.StatusNotFound, "id not found")
@@ -24,8 +24,21 @@ func (h *handler) ReactionsAdder(c echo.Context) error {
 		c.Logger().Error("Failed to retrieve id:", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve id")
 	} //404以外は500に
+	//公開されていないメッセージは存在しない扱い
+	if msg.Status != domain.MessageStatusPublished {
+		return echo.NewHTTPError(http.StatusNotFound, "id not found")
+	}
 	//ユーザーネームの取得
 	username := c.Get("username").(string)
+	//ブロックされていたらリアクションできない
+	blocked, err := h.repo.IsBlocking(msg.Author, username)
+	if err != nil {
+		c.Logger().Error("Failed to retrieve block state:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve block state")
+	}
+	if blocked {
+		return echo.NewHTTPError(http.StatusForbidden, "blocked by the author")
+	}
 	//リアクションを追加
 	_, err = h.repo.InsertMessageReaction(ID, username)
 	if err != nil {
@@ -35,6 +48,8 @@ func (h *handler) ReactionsAdder(c echo.Context) error {
 		c.Logger().Error("failed to insert reaction:", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to insert reaction")
 	} //409以外
+	//投稿者に通知
+	h.notify(c.Logger(), msg.Author, domain.NotificationTypeReaction, username, ID)
 	//リアクションの数の取得
 	s, err := h.repo.GetReactionsToMessage(ID)
 	if err != nil {
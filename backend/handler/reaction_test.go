@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+func (r *messageRepo) IsBlocking(blocker, blocked string) (bool, error) {
+	return r.blocks[[2]string{blocker, blocked}], nil
+}
+
+func (r *messageRepo) InsertMessageReaction(messageID uuid.UUID, username string) (*domain.MessageReaction, error) {
+	r.written = true
+	return nil, errors.New("unexpected write")
+}
+
+func (r *messageRepo) InsertRepost(messageID uuid.UUID, username string) error {
+	r.written = true
+	return errors.New("unexpected write")
+}
+
+func (r *messageRepo) InsertBookmark(messageID uuid.UUID, username string) error {
+	r.written = true
+	return errors.New("unexpected write")
+}
+
+func (r *messageRepo) PinMessage(messageID uuid.UUID, username string) error {
+	r.written = true
+	return errors.New("unexpected write")
+}
+
+func TestActionsOnUnpublishedMessagesAreNotFound(t *testing.T) {
+	repo := &messageRepo{messages: map[uuid.UUID]*domain.Message{}}
+	h := newTestHandler(t, repo)
+
+	handlers := map[string]echo.HandlerFunc{
+		"reaction": h.ReactionsAdder,
+		"repost":   h.RepostAdder,
+		"bookmark": h.BookmarkAdder,
+		"pin":      h.PinMessageHandler,
+	}
+	statuses := []domain.MessageStatus{domain.MessageStatusScheduled, domain.MessageStatusCanceled, domain.MessageStatusHidden}
+	for name, handle := range handlers {
+		for _, status := range statuses {
+			t.Run(name+"/"+string(status), func(t *testing.T) {
+				id := uuid.New()
+				repo.messages[id] = &domain.Message{ID: id, Author: "alice", Status: status}
+				repo.written = false
+
+				c, _ := newTestContext(http.MethodPost, "/", "alice", "id", id.String())
+				err := handle(c)
+				var httpErr *echo.HTTPError
+				if !errors.As(err, &httpErr) || httpErr.Code != http.StatusNotFound {
+					t.Fatalf("error = %v, want 404", err)
+				}
+				if repo.written {
+					t.Fatal("wrote to an unpublished message")
+				}
+			})
+		}
+	}
+}
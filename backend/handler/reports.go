@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+type report struct {
+	ID        uuid.UUID `json:"id"`
+	Reporter  string    `json:"reporter"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type reportedMessage struct {
+	Message        message   `json:"message"`
+	ReportCount    int64     `json:"reportCount"`
+	LastReportedAt time.Time `json:"lastReportedAt"`
+	Reports        []report  `json:"reports"`
+}
+
+type moderationLog struct {
+	ID             uuid.UUID  `json:"id"`
+	Moderator      string     `json:"moderator"`
+	Action         string     `json:"action"`
+	MessageID      *uuid.UUID `json:"messageId"`
+	TargetUser     string     `json:"targetUser"`
+	SuspendedUntil *time.Time `json:"suspendedUntil"`
+	Note           string     `json:"note"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+func toReport(r domain.Report) report {
+	return report{
+		ID:        r.ID,
+		Reporter:  r.Reporter,
+		Reason:    r.Reason,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func (h *handler) ReportMessageHandler(ctx echo.Context) error {
+	ID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
+	}
+	var reqBody struct {
+		Reason string `json:"reason"`
+	}
+	if err := ctx.Bind(&reqBody); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	reason := strings.TrimSpace(reqBody.Reason)
+	if reason == "" || utf8.RuneCountInString(reason) > domain.MaxReportReasonLength {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("reason must be 1 to %d characters", domain.MaxReportReasonLength))
+	}
+
+	msg, err := h.repo.GetMessageByID(ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "id not found")
+		}
+		ctx.Logger().Error("Failed to retrieve message:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve message")
+	}
+	if msg.Status != domain.MessageStatusPublished {
+		return echo.NewHTTPError(http.StatusNotFound, "id not found")
+	}
+
+	username := ctx.Get(m.UsernameKey).(string)
+	r, err := h.repo.CreateReport(ID, username, reason)
+	if err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			return echo.NewHTTPError(http.StatusConflict, "already reported")
+		}
+		ctx.Logger().Error("Failed to create report:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create report")
+	}
+	return ctx.JSON(http.StatusCreated, toReport(*r))
+}
+
+// GetReportQueueHandler は未対応の通報があるメッセージを通報の多い順に返す
+// 非表示にされたメッセージも含めるので、メッセージの中身は作者以外にも見える
+func (h *handler) GetReportQueueHandler(ctx echo.Context) error {
+	limit, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("limit"), "20"), 10, 64)
+	if err != nil || limit <= 0 || limit > 100 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit parameter")
+	}
+	offset, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("offset"), "0"), 10, 64)
+	if err != nil || offset < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offset parameter")
+	}
+
+	queue, err := h.repo.GetReportQueue(limit, offset)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve report queue:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve report queue")
+	}
+	result := make([]reportedMessage, len(queue))
+	for i, q := range queue {
+		msg, err := h.buildMessage(ctx, q.Message, true)
+		if err != nil {
+			return err
+		}
+		reports, err := h.repo.GetOpenReports(q.Message.ID)
+		if err != nil {
+			ctx.Logger().Error("Failed to retrieve reports:", q.Message.ID, err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve reports")
+		}
+		result[i] = reportedMessage{
+			Message:        msg,
+			ReportCount:    q.ReportCount,
+			LastReportedAt: q.LastReportedAt,
+			Reports:        make([]report, len(reports)),
+		}
+		for j := range reports {
+			result[i].Reports[j] = toReport(reports[j])
+		}
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+// ResolveReportsHandler はメッセージへの未対応の通報をまとめて処理する
+func (h *handler) ResolveReportsHandler(ctx echo.Context) error {
+	ID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
+	}
+	var reqBody struct {
+		Action        string `json:"action"`
+		DurationHours int64  `json:"durationHours"`
+		Note          string `json:"note"`
+	}
+	if err := ctx.Bind(&reqBody); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	note := strings.TrimSpace(reqBody.Note)
+	if utf8.RuneCountInString(note) > domain.MaxReportReasonLength {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("note must be at most %d characters", domain.MaxReportReasonLength))
+	}
+
+	msg, err := h.repo.GetMessageByID(ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "id not found")
+		}
+		ctx.Logger().Error("Failed to retrieve message:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve message")
+	}
+
+	log := domain.ModerationLog{
+		Moderator:  ctx.Get(m.UsernameKey).(string),
+		Action:     domain.ModerationAction(reqBody.Action),
+		MessageID:  ID,
+		TargetUser: msg.Author,
+		Note:       note,
+	}
+	switch log.Action {
+	case domain.ModerationActionDismiss, domain.ModerationActionHide:
+	case domain.ModerationActionSuspend:
+		// 時間に直すとあふれるので、掛ける前に時間数で比べる
+		maxHours := int64(domain.MaxSuspension / time.Hour)
+		if reqBody.DurationHours <= 0 || reqBody.DurationHours > maxHours {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("durationHours must be 1 to %d", maxHours))
+		}
+		log.SuspendedUntil = time.Now().Add(time.Duration(reqBody.DurationHours) * time.Hour).UTC().Truncate(time.Second)
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "action must be one of dismiss, hide, suspend")
+	}
+
+	if err := h.repo.ResolveReports(log); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "no open reports for this message")
+		}
+		ctx.Logger().Error("Failed to resolve reports:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to resolve reports")
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (h *handler) GetModerationLogsHandler(ctx echo.Context) error {
+	limit, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("limit"), "20"), 10, 64)
+	if err != nil || limit <= 0 || limit > 100 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit parameter")
+	}
+	offset, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("offset"), "0"), 10, 64)
+	if err != nil || offset < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offset parameter")
+	}
+
+	logs, err := h.repo.GetModerationLogs(limit, offset)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve moderation logs:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve moderation logs")
+	}
+	result := make([]moderationLog, len(logs))
+	for i, l := range logs {
+		result[i] = moderationLog{
+			ID:         l.ID,
+			Moderator:  l.Moderator,
+			Action:     string(l.Action),
+			TargetUser: l.TargetUser,
+			Note:       l.Note,
+			CreatedAt:  l.CreatedAt,
+		}
+		if l.MessageID != uuid.Nil {
+			result[i].MessageID = &l.MessageID
+		}
+		if !l.SuspendedUntil.IsZero() {
+			result[i].SuspendedUntil = &l.SuspendedUntil
+		}
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
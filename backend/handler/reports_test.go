@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+// reportRepo は ResolveReports に渡された記録を残す
+type reportRepo struct {
+	messageRepo
+	resolved []domain.ModerationLog
+}
+
+func (r *reportRepo) ResolveReports(log domain.ModerationLog) error {
+	r.resolved = append(r.resolved, log)
+	return nil
+}
+
+func TestResolveReportsHandlerSuspensionDuration(t *testing.T) {
+	maxHours := int64(domain.MaxSuspension / time.Hour)
+	tests := []struct {
+		name     string
+		body     string
+		want     int
+		wantDays int
+	}{
+		{"one day", `{"action":"suspend","durationHours":24}`, http.StatusNoContent, 1},
+		{"at the limit", `{"action":"suspend","durationHours":8760}`, http.StatusNoContent, 365},
+		{"zero", `{"action":"suspend","durationHours":0}`, http.StatusBadRequest, 0},
+		{"negative", `{"action":"suspend","durationHours":-1}`, http.StatusBadRequest, 0},
+		{"over the limit", `{"action":"suspend","durationHours":8761}`, http.StatusBadRequest, 0},
+		// 時間に直すと int64 があふれて小さな値や負の値になる
+		{"overflows to negative", `{"action":"suspend","durationHours":2562048}`, http.StatusBadRequest, 0},
+		{"overflows to small", `{"action":"suspend","durationHours":5124096}`, http.StatusBadRequest, 0},
+		{"max int64", `{"action":"suspend","durationHours":9223372036854775807}`, http.StatusBadRequest, 0},
+	}
+	if maxHours != 8760 {
+		t.Fatalf("MaxSuspension = %d hours, test cases assume 8760", maxHours)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := uuid.New()
+			repo := &reportRepo{messageRepo: messageRepo{messages: map[uuid.UUID]*domain.Message{
+				id: {ID: id, Author: "alice", Status: domain.MessageStatusPublished},
+			}}}
+			h := newTestHandler(t, repo)
+			c, rec := newTestContext(http.MethodPost, "/", "mod", "id", id.String())
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			c.SetRequest(req)
+
+			err := h.ResolveReportsHandler(c)
+			code := rec.Code
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				code = httpErr.Code
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.want {
+				t.Fatalf("status = %d, want %d", code, tt.want)
+			}
+			if tt.want != http.StatusNoContent {
+				if len(repo.resolved) != 0 {
+					t.Fatalf("resolved = %+v, want none", repo.resolved)
+				}
+				return
+			}
+			if len(repo.resolved) != 1 {
+				t.Fatalf("resolved = %+v, want one", repo.resolved)
+			}
+			want := time.Now().Add(time.Duration(tt.wantDays) * 24 * time.Hour)
+			if got := repo.resolved[0].SuspendedUntil; got.Sub(want).Abs() > time.Minute {
+				t.Fatalf("SuspendedUntil = %v, want about %v", got, want)
+			}
+		})
+	}
+}
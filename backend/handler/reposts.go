@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+func (h *handler) RepostAdder(c echo.Context) error {
+	ID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
+	}
+	msg, err := h.repo.GetMessageByID(ID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "id not found")
+		}
+		c.Logger().Error("Failed to retrieve message:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve message")
+	}
+	if msg.Status != domain.MessageStatusPublished {
+		return echo.NewHTTPError(http.StatusNotFound, "id not found")
+	}
+	username := c.Get(m.UsernameKey).(string)
+	if err := h.repo.InsertRepost(ID, username); err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			return echo.NewHTTPError(http.StatusConflict, "already reposted")
+		}
+		c.Logger().Error("Failed to insert repost:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to insert repost")
+	}
+	return h.respondReposts(c, http.StatusCreated, ID, username)
+}
+
+func (h *handler) RepostDeleter(c echo.Context) error {
+	ID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
+	}
+	username := c.Get(m.UsernameKey).(string)
+	if err := h.repo.DeleteRepost(ID, username); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "repost not found")
+		}
+		c.Logger().Error("Failed to delete repost:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete repost")
+	}
+	return h.respondReposts(c, http.StatusOK, ID, username)
+}
+
+func (h *handler) respondReposts(c echo.Context, code int, messageID uuid.UUID, username string) error {
+	list, err := h.repo.GetRepostsOfMessage(messageID)
+	if err != nil {
+		c.Logger().Error("Failed to get reposts:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get reposts")
+	}
+	return c.JSON(code, reposts{
+		Count: int64(len(list)),
+		MyRepost: slices.ContainsFunc(list, func(r *domain.Repost) bool {
+			return r.Username == username
+		}),
+	})
+}
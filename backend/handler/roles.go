@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+// loadEnvRoles は ADMIN_USERS と MODERATOR_USERS (カンマ区切りの traQ ID) を読む
+// データベースの role と両方設定されている場合は強い方が使われる
+func loadEnvRoles() map[string]domain.Role {
+	roles := map[string]domain.Role{}
+	for _, entry := range []struct {
+		env  string
+		role domain.Role
+	}{
+		{"MODERATOR_USERS", domain.RoleModerator},
+		{"ADMIN_USERS", domain.RoleAdmin},
+	} {
+		for _, username := range strings.Split(os.Getenv(entry.env), ",") {
+			if username = strings.TrimSpace(username); username != "" {
+				roles[username] = domain.HigherRole(roles[username], entry.role)
+			}
+		}
+	}
+	return roles
+}
+
+// roleOf はユーザーの権限を返す
+func (h *handler) roleOf(username string) (domain.Role, error) {
+	role, err := h.repo.GetUserRole(username)
+	if err != nil {
+		return "", err
+	}
+	if envRole, ok := h.envRoles[username]; ok {
+		role = domain.HigherRole(role, envRole)
+	}
+	return role, nil
+}
+
+// RequireRole はリクエストしたユーザーが role 以上の権限を持っていなければ 403 を返す
+func (h *handler) RequireRole(role domain.Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			username := c.Get(m.UsernameKey).(string)
+			actual, err := h.roleOf(username)
+			if err != nil {
+				c.Logger().Error("Failed to retrieve user role:", username, err)
+				return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve role")
+			}
+			if !actual.AtLeast(role) {
+				return echo.NewHTTPError(http.StatusForbidden, "insufficient role")
+			}
+			return next(c)
+		}
+	}
+}
+
+func (h *handler) SetUserRoleHandler(ctx echo.Context) error {
+	username := ctx.Param("name")
+	var reqBody struct {
+		Role string `json:"role"`
+	}
+	if err := ctx.Bind(&reqBody); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	role := domain.Role(reqBody.Role)
+	if !role.Valid() {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid role")
+	}
+	if err := h.repo.SetUserRole(username, role); err != nil {
+		ctx.Logger().Error("Failed to set user role:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to set role")
+	}
+	// 環境変数で決めた権限はデータベースより強いので、実際に使われる権限を返す
+	actual, err := h.roleOf(username)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve user role:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve role")
+	}
+	return ctx.JSON(http.StatusOK, map[string]string{"traqId": username, "role": string(actual)})
+}
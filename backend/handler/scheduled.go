@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+// schedulerPollInterval は予約投稿がなくてもデータベースを確認し直す間隔
+// 他のプロセスで作られた予約投稿もこの間隔で拾う
+const schedulerPollInterval = time.Minute
+
+// schedulerMinWait は公開日時を過ぎた予約投稿が残っていても、次に確認するまでに待つ最短の時間
+// 公開に失敗した予約投稿は残り続けるので、待たずに繰り返すとデータベースに問い合わせ続けてしまう
+const schedulerMinWait = time.Second
+
+type scheduledMessage struct {
+	message
+	PublishAt time.Time `json:"publishAt"`
+}
+
+func publishAtPtr(msg *domain.Message) *time.Time {
+	if msg.PublishAt.IsZero() {
+		return nil
+	}
+	return &msg.PublishAt
+}
+
+// wakeScheduler は予約投稿が増えたことをスケジューラーに知らせる
+func (h *handler) wakeScheduler() {
+	select {
+	case h.schedulerWake <- struct{}{}:
+	default:
+	}
+}
+
+// runScheduler は公開日時を迎えた予約投稿を公開し続ける
+// 状態はすべてデータベースにあるので、再起動しても止まっていた間の予約投稿から再開できる
+func (h *handler) runScheduler(logger echo.Logger) {
+	var backoff time.Duration
+	for {
+		var wait time.Duration
+		wait, backoff = h.schedulerStep(logger, backoff)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-h.schedulerWake:
+			timer.Stop()
+		}
+	}
+}
+
+// schedulerStep は公開日時を迎えた予約投稿を公開し、次に確認するまで待つ時間を返す
+// 失敗があった回は backoff を schedulerMinWait から schedulerPollInterval まで倍にしていき、その間は待つ
+func (h *handler) schedulerStep(logger echo.Logger, backoff time.Duration) (wait, nextBackoff time.Duration) {
+	ok := h.publishDueMessages(logger)
+
+	wait = schedulerPollInterval
+	next, err := h.repo.GetNextPublishAt()
+	if err != nil {
+		logger.Error("Failed to retrieve next publish time:", err)
+		ok = false
+	} else if !next.IsZero() {
+		wait = min(wait, time.Until(next))
+	}
+
+	if ok {
+		backoff = 0
+	} else {
+		backoff = min(max(2*backoff, schedulerMinWait), schedulerPollInterval)
+	}
+	return max(wait, schedulerMinWait, backoff), backoff
+}
+
+// publishDueMessages は公開日時を迎えた予約投稿を公開する。失敗した予約投稿があれば false を返す
+func (h *handler) publishDueMessages(logger echo.Logger) bool {
+	messages, err := h.repo.GetDueScheduledMessages(time.Now())
+	if err != nil {
+		logger.Error("Failed to retrieve scheduled messages:", err)
+		return false
+	}
+	ok := true
+	now := time.Now()
+	for i := range messages {
+		msg := &messages[i]
+		// 予約した後に投稿停止になった作者の予約投稿は公開せずに取り消す
+		// (残しておくと公開日時を過ぎたまま何度も選ばれ続ける)
+		suspendedUntil, err := h.repo.GetSuspendedUntil(msg.Author)
+		if err != nil {
+			logger.Error("Failed to retrieve suspension:", msg.Author, err)
+			ok = false
+			continue
+		}
+		if suspendedUntil.After(now) {
+			if err := h.repo.SetMessageStatus(msg.ID, domain.MessageStatusScheduled, domain.MessageStatusCanceled); err != nil {
+				// domain.ErrNotFound なら他で取り消されたか公開された
+				if !errors.Is(err, domain.ErrNotFound) {
+					logger.Error("Failed to cancel scheduled message of suspended user:", msg.ID, err)
+					ok = false
+				}
+				continue
+			}
+			logger.Info("Canceled scheduled message of suspended user:", msg.ID, msg.Author)
+			h.releaseMessageImage(context.Background(), logger, msg.ID)
+			continue
+		}
+
+		published, err := h.repo.PublishScheduledMessage(msg.ID)
+		if err != nil {
+			logger.Error("Failed to publish scheduled message:", msg.ID, err)
+			ok = false
+			continue
+		}
+		if !published {
+			continue
+		}
+
+		parentAuthor := ""
+		if msg.ParentID != uuid.Nil {
+			parent, err := h.repo.GetMessageByID(msg.ParentID)
+			if err != nil {
+				logger.Error("Failed to retrieve parent message:", msg.ParentID, err)
+			} else {
+				parentAuthor = parent.Author
+			}
+		}
+		h.notifyNewMessage(logger, msg, parentAuthor)
+	}
+	return ok
+}
+
+func (h *handler) GetMyScheduledMessagesHandler(ctx echo.Context) error {
+	username := ctx.Get(m.UsernameKey).(string)
+	messages, err := h.repo.GetScheduledMessages(username)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve scheduled messages:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve scheduled messages")
+	}
+	result := make([]scheduledMessage, len(messages))
+	for i, msg := range messages {
+		built, err := h.buildMessage(ctx, msg, true)
+		if err != nil {
+			return err
+		}
+		result[i] = scheduledMessage{message: built, PublishAt: msg.PublishAt}
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+func (h *handler) CancelScheduledMessageHandler(ctx echo.Context) error {
+	ID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
+	}
+	username := ctx.Get(m.UsernameKey).(string)
+	if err := h.repo.CancelScheduledMessage(ID, username); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "scheduled message not found")
+		}
+		ctx.Logger().Error("Failed to cancel scheduled message:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to cancel scheduled message")
+	}
+	// 取り消した予約投稿は二度と表示されないので、添付画像はここで手放す
+	h.releaseMessageImage(ctx.Request().Context(), ctx.Logger(), ID)
+	return ctx.NoContent(http.StatusNoContent)
+}
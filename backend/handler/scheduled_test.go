@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+// imageRepo は画像の参照カウントをメモリ上で再現する
+type imageRepo struct {
+	fakeRepo
+	messageImages map[uuid.UUID]uuid.UUID
+	imageHashes   map[uuid.UUID]string
+	refCounts     map[string]int
+}
+
+func (r *imageRepo) CancelScheduledMessage(messageID uuid.UUID, author string) error {
+	return nil
+}
+
+func (r *imageRepo) GetMessageImageIDByMessageID(messageID uuid.UUID) (uuid.UUID, error) {
+	imageID, ok := r.messageImages[messageID]
+	if !ok {
+		return uuid.Nil, domain.ErrNotFound
+	}
+	return imageID, nil
+}
+
+func (r *imageRepo) DeleteMessageImage(imageID uuid.UUID, deleteBlob func(key string)) error {
+	hash, ok := r.imageHashes[imageID]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	delete(r.imageHashes, imageID)
+	r.refCounts[hash]--
+	if r.refCounts[hash] > 0 {
+		return nil
+	}
+	delete(r.refCounts, hash)
+	deleteBlob(hash)
+	return nil
+}
+
+func TestCancelScheduledMessageReleasesImage(t *testing.T) {
+	const hash = "0123456789abcdef"
+	first, second, noImage := uuid.New(), uuid.New(), uuid.New()
+	repo := &imageRepo{
+		messageImages: map[uuid.UUID]uuid.UUID{},
+		imageHashes:   map[uuid.UUID]string{},
+		refCounts:     map[string]int{hash: 2},
+	}
+	for _, messageID := range []uuid.UUID{first, second} {
+		imageID := uuid.New()
+		repo.messageImages[messageID] = imageID
+		repo.imageHashes[imageID] = hash
+	}
+	h := newTestHandler(t, repo)
+	if err := h.blobs.Put(context.Background(), hash, strings.NewReader("image"), 5, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		messageID uuid.UUID
+		blobKept  bool
+	}{
+		{"message without image", noImage, true},
+		{"image still referenced by another message", first, true},
+		{"last reference", second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := newTestContext(http.MethodDelete, "/", "alice", "id", tt.messageID.String())
+			if err := h.CancelScheduledMessageHandler(c); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusNoContent {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+			}
+			blob, err := h.blobs.Get(context.Background(), hash)
+			if err == nil {
+				blob.Close()
+			}
+			if kept := !errors.Is(err, domain.ErrNotFound); kept != tt.blobKept {
+				t.Fatalf("blob kept = %v, want %v (err: %v)", kept, tt.blobKept, err)
+			}
+		})
+	}
+}
+
+// schedulerRepo は公開日時を迎えた予約投稿と投稿停止をメモリ上で再現する
+type schedulerRepo struct {
+	fakeRepo
+	due            []domain.Message
+	suspendedUntil map[string]time.Time
+	status         map[uuid.UUID]domain.MessageStatus
+	publishErr     error
+	next           time.Time
+}
+
+func (r *schedulerRepo) GetNextPublishAt() (time.Time, error) {
+	return r.next, nil
+}
+
+func (r *schedulerRepo) GetDueScheduledMessages(now time.Time) ([]domain.Message, error) {
+	return r.due, nil
+}
+
+func (r *schedulerRepo) GetSuspendedUntil(username string) (time.Time, error) {
+	return r.suspendedUntil[username], nil
+}
+
+func (r *schedulerRepo) SetMessageStatus(id uuid.UUID, from, to domain.MessageStatus) error {
+	if r.status[id] != from {
+		return domain.ErrNotFound
+	}
+	r.status[id] = to
+	return nil
+}
+
+func (r *schedulerRepo) PublishScheduledMessage(messageID uuid.UUID) (bool, error) {
+	if r.publishErr != nil {
+		return false, r.publishErr
+	}
+	if r.status[messageID] != domain.MessageStatusScheduled {
+		return false, nil
+	}
+	r.status[messageID] = domain.MessageStatusPublished
+	return true, nil
+}
+
+func (r *schedulerRepo) GetMessageImageIDByMessageID(messageID uuid.UUID) (uuid.UUID, error) {
+	return uuid.Nil, domain.ErrNotFound
+}
+
+func TestPublishDueMessagesSkipsSuspendedAuthors(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name           string
+		suspendedUntil time.Time
+		want           domain.MessageStatus
+	}{
+		{"not suspended", time.Time{}, domain.MessageStatusPublished},
+		{"suspension has ended", now.Add(-time.Hour), domain.MessageStatusPublished},
+		{"suspended", now.Add(time.Hour), domain.MessageStatusCanceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := domain.Message{ID: uuid.New(), Author: "alice", Content: "later", Status: domain.MessageStatusScheduled}
+			repo := &schedulerRepo{
+				due:            []domain.Message{msg},
+				suspendedUntil: map[string]time.Time{"alice": tt.suspendedUntil},
+				status:         map[uuid.UUID]domain.MessageStatus{msg.ID: domain.MessageStatusScheduled},
+			}
+			h := newTestHandler(t, repo)
+			h.publishDueMessages(echo.New().Logger)
+			if got := repo.status[msg.ID]; got != tt.want {
+				t.Fatalf("status = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulerStepBacksOffWhilePublishingFails(t *testing.T) {
+	msg := domain.Message{ID: uuid.New(), Author: "alice", Content: "later", Status: domain.MessageStatusScheduled}
+	repo := &schedulerRepo{
+		due:        []domain.Message{msg},
+		status:     map[uuid.UUID]domain.MessageStatus{msg.ID: domain.MessageStatusScheduled},
+		publishErr: errors.New("db down"),
+		// 公開できなかった予約投稿は公開日時を過ぎたまま残る
+		next: time.Now().Add(-time.Minute),
+	}
+	h := newTestHandler(t, repo)
+	logger := echo.New().Logger
+
+	var backoff time.Duration
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second, schedulerPollInterval, schedulerPollInterval} {
+		var wait time.Duration
+		wait, backoff = h.schedulerStep(logger, backoff)
+		if wait != want {
+			t.Fatalf("wait = %v, want %v", wait, want)
+		}
+	}
+
+	// 公開できるようになったら公開日時どおりに戻る
+	repo.publishErr = nil
+	repo.next = time.Now().Add(10 * time.Second)
+	wait, backoff := h.schedulerStep(logger, backoff)
+	if backoff != 0 || wait > 10*time.Second || wait < schedulerMinWait {
+		t.Fatalf("wait, backoff = %v, %v after recovery", wait, backoff)
+	}
+	if got := repo.status[msg.ID]; got != domain.MessageStatusPublished {
+		t.Fatalf("status = %s, want published", got)
+	}
+}
+
+func TestSchedulerStepWaitsAtLeastMinWait(t *testing.T) {
+	repo := &schedulerRepo{next: time.Now().Add(-time.Hour)}
+	h := newTestHandler(t, repo)
+	wait, backoff := h.schedulerStep(echo.New().Logger, 0)
+	if wait != schedulerMinWait || backoff != 0 {
+		t.Fatalf("wait, backoff = %v, %v, want %v, 0", wait, backoff, schedulerMinWait)
+	}
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	"github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+// スニペットに含める、最初に一致した位置より前の文字数と全体の文字数
+const (
+	snippetBefore = 40
+	snippetLength = 140
+)
+
+type highlight struct {
+	Offset int `json:"offset"` // スニペット内の位置(文字数)
+	Length int `json:"length"`
+}
+
+type searchResult struct {
+	message
+	Snippet    string      `json:"snippet"`
+	Highlights []highlight `json:"highlights"`
+}
+
+func (h *handler) SearchMessagesHandler(ctx echo.Context) error {
+	limit, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("limit"), "20"), 10, 64)
+	if err != nil || limit <= 0 || limit > 100 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit parameter")
+	}
+	offset, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("offset"), "0"), 10, 64)
+	if err != nil || offset < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offset parameter")
+	}
+
+	query := parseSearchQuery(ctx.QueryParam("q"))
+	if len(query.Terms) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Search query is empty")
+	}
+	query.Authors = ctx.QueryParams()["author"]
+	if since := ctx.QueryParam("since"); since != "" {
+		query.Since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid since parameter")
+		}
+	}
+	if until := ctx.QueryParam("until"); until != "" {
+		query.Until, err = time.Parse(time.RFC3339, until)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid until parameter")
+		}
+	}
+	switch order := domain.SearchOrder(cmp.Or(ctx.QueryParam("order"), string(domain.SearchOrderRelevance))); order {
+	case domain.SearchOrderRelevance, domain.SearchOrderRecent:
+		query.Order = order
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid order parameter")
+	}
+	query.Limit = limit
+	query.Offset = offset
+	query.Viewer = ctx.Get(middleware.UsernameKey).(string)
+
+	messages, err := h.repo.SearchMessages(query)
+	if err != nil {
+		ctx.Logger().Error("Failed to search messages:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to search messages")
+	}
+	jsonMessages, err := h.buildMessages(ctx, messages)
+	if err != nil {
+		return err
+	}
+
+	results := make([]searchResult, len(jsonMessages))
+	for i, m := range jsonMessages {
+		snippet, highlights := makeSnippet(m.Content, query.Terms)
+		results[i] = searchResult{
+			message:    m,
+			Snippet:    snippet,
+			Highlights: highlights,
+		}
+	}
+	return ctx.JSON(http.StatusOK, results)
+}
+
+// parseSearchQuery は検索文字列を解釈する
+//
+//	foo bar   foo と bar の両方を含む
+//	"foo bar" foo bar というフレーズを含む
+//	-foo      foo を含まない (-"foo bar" も可)
+func parseSearchQuery(q string) domain.MessageSearchQuery {
+	var query domain.MessageSearchQuery
+	rest := strings.TrimSpace(q)
+	for rest != "" {
+		exclude := false
+		if strings.HasPrefix(rest, "-") {
+			exclude = true
+			rest = rest[1:]
+		}
+
+		var term string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				term, rest = rest[1:], ""
+			} else {
+				term, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(rest, unicode.IsSpace)
+			if end < 0 {
+				term, rest = rest, ""
+			} else {
+				term, rest = rest[:end], rest[end:]
+			}
+		}
+		rest = strings.TrimSpace(rest)
+
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		if exclude {
+			query.Excludes = append(query.Excludes, term)
+		} else {
+			query.Terms = append(query.Terms, term)
+		}
+	}
+	return query
+}
+
+// makeSnippet は本文のうち最初に語句が一致した付近を切り出し、一致した箇所の位置を返す
+func makeSnippet(content string, terms []string) (string, []highlight) {
+	runes := []rune(content)
+	lower := []rune(strings.ToLower(content))
+	if len(lower) != len(runes) {
+		// 小文字にすると文字数が変わる場合は位置を合わせられないので大文字小文字を区別する
+		lower = runes
+	}
+
+	type match struct{ start, end int }
+	var matches []match
+	for _, t := range terms {
+		tr := []rune(strings.ToLower(t))
+		if len(tr) == 0 {
+			continue
+		}
+		for i := 0; i+len(tr) <= len(lower); i++ {
+			if string(lower[i:i+len(tr)]) == string(tr) {
+				matches = append(matches, match{i, i + len(tr)})
+				i += len(tr) - 1
+			}
+		}
+	}
+
+	start := 0
+	if len(matches) > 0 {
+		first := matches[0].start
+		for _, m := range matches {
+			first = min(first, m.start)
+		}
+		start = max(0, first-snippetBefore)
+	}
+	end := min(len(runes), start+snippetLength)
+
+	highlights := []highlight{}
+	for _, m := range matches {
+		if m.start < start || m.end > end {
+			continue
+		}
+		highlights = append(highlights, highlight{Offset: m.start - start, Length: m.end - m.start})
+	}
+	slices.SortFunc(highlights, func(a, b highlight) int {
+		return cmp.Compare(a.Offset, b.Offset)
+	})
+
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "…" + snippet
+		for i := range highlights {
+			highlights[i].Offset++
+		}
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet, highlights
+}
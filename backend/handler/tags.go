@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"cmp"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+type tagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+func (h *handler) GetTagMessagesHandler(ctx echo.Context) error {
+	tag := ctx.Param("tag")
+	if tag == "" {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	limit, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("limit"), "20"), 10, 64)
+	if err != nil || limit <= 0 || limit > 100 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit parameter")
+	}
+	offset, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("offset"), "0"), 10, 64)
+	if err != nil || offset < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid offset parameter")
+	}
+
+	messages, err := h.repo.GetMessagesByTag(tag, limit, offset, ctx.Get(m.UsernameKey).(string))
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve messages by tag:", tag, err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	jsonMessages, err := h.buildMessages(ctx, messages)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, jsonMessages)
+}
+
+func (h *handler) GetTrendingTagsHandler(ctx echo.Context) error {
+	// 集計する期間(時間)。最大で1週間
+	hours, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("hours"), "24"), 10, 64)
+	if err != nil || hours <= 0 || hours > 24*7 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid hours parameter")
+	}
+	limit, err := strconv.ParseInt(cmp.Or(ctx.QueryParam("limit"), "10"), 10, 64)
+	if err != nil || limit <= 0 || limit > 100 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit parameter")
+	}
+
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	counts, err := h.repo.GetTrendingTags(since, limit)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve trending tags:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+	result := make([]tagCount, len(counts))
+	for i, c := range counts {
+		result[i] = tagCount{
+			Tag:   c.Tag,
+			Count: c.Count,
+		}
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
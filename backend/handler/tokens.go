@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+// tokenPrefix はパーソナルアクセストークンの先頭に付ける。設定で決めたトークンと区別するのと、漏洩したときに見つけやすくするため
+const tokenPrefix = "h25s09_pat_"
+
+type personalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+type createdToken struct {
+	personalAccessToken
+	// Token は作成したときにだけ返す
+	Token string `json:"token"`
+}
+
+func toPersonalAccessToken(t *domain.PersonalAccessToken) personalAccessToken {
+	scopes := make([]string, len(t.Scopes))
+	for i, s := range t.Scopes {
+		scopes[i] = string(s)
+	}
+	result := personalAccessToken{
+		ID:        t.ID,
+		Name:      t.Name,
+		Scopes:    scopes,
+		CreatedAt: t.CreatedAt,
+	}
+	if !t.LastUsedAt.IsZero() {
+		result.LastUsedAt = &t.LastUsedAt
+	}
+	return result
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ResolveToken はパーソナルアクセストークンの持ち主を返す (m.TokenResolver)
+func (h *handler) ResolveToken(_ context.Context, token string) (*m.Identity, error) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return nil, m.ErrInvalidCredentials
+	}
+	t, err := h.repo.GetTokenByHash(hashToken(token))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, fmt.Errorf("%w: unknown or revoked token", m.ErrInvalidCredentials)
+		}
+		return nil, err
+	}
+	// 毎回書き込まないよう、最終使用日時は1分単位で更新する
+	if now := time.Now(); now.Sub(t.LastUsedAt) > time.Minute {
+		_ = h.repo.TouchToken(t.ID, now)
+	}
+	scopes := make([]string, len(t.Scopes))
+	for i, s := range t.Scopes {
+		scopes[i] = string(s)
+	}
+	return &m.Identity{
+		Username: t.Username,
+		Provider: "token",
+		TokenID:  t.ID,
+		Scopes:   scopes,
+	}, nil
+}
+
+// tokenIDOf はリクエストがパーソナルアクセストークンで認証されていればそのIDを、そうでなければ uuid.Nil を返す
+func tokenIDOf(c echo.Context) uuid.UUID {
+	identity, ok := c.Get(m.IdentityKey).(*m.Identity)
+	if !ok {
+		return uuid.Nil
+	}
+	return identity.TokenID
+}
+
+// requiredTokenScope はトークンでそのAPIを使うのに必要なスコープを返す
+// トークンで使えないAPIなら false を返す (トークンの作成などは必ずブラウザから行う)
+func requiredTokenScope(method, path string) (domain.TokenScope, bool) {
+	if method == http.MethodGet || method == http.MethodHead {
+		return domain.TokenScopeRead, !strings.HasPrefix(path, "/api/me/tokens")
+	}
+	switch method + " " + path {
+	case "POST /api/messages":
+		return domain.TokenScopePost, true
+	case "POST /api/messages/:id/reactions", "DELETE /api/messages/:id/reactions", "POST /api/messages/:id/poll/votes":
+		return domain.TokenScopeReact, true
+	}
+	return "", false
+}
+
+// TokenScopeChecker はパーソナルアクセストークンで認証されたリクエストのスコープを確かめる
+// UsernameProvider の後に使う
+func (h *handler) TokenScopeChecker(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		identity, ok := c.Get(m.IdentityKey).(*m.Identity)
+		if !ok || identity.TokenID == uuid.Nil {
+			return next(c)
+		}
+		scope, allowed := requiredTokenScope(c.Request().Method, c.Path())
+		if !allowed {
+			return echo.NewHTTPError(http.StatusForbidden, "this API cannot be used with a token")
+		}
+		if !slices.Contains(identity.Scopes, string(scope)) {
+			return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("token does not have %q scope", scope))
+		}
+		return next(c)
+	}
+}
+
+func (h *handler) CreateTokenHandler(ctx echo.Context) error {
+	username := ctx.Get(m.UsernameKey).(string)
+
+	var reqBody struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := ctx.Bind(&reqBody); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+	name := strings.TrimSpace(reqBody.Name)
+	if name == "" || utf8.RuneCountInString(name) > domain.MaxTokenNameLength {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("name must be 1 to %d characters", domain.MaxTokenNameLength))
+	}
+	if len(reqBody.Scopes) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "scopes must not be empty")
+	}
+	scopes := []domain.TokenScope{}
+	for _, s := range reqBody.Scopes {
+		scope := domain.TokenScope(s)
+		if !slices.Contains(domain.TokenScopes, scope) {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown scope %q", s))
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		ctx.Logger().Error("Failed to generate token:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to generate token")
+	}
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	t, err := h.repo.CreateToken(username, name, scopes, hashToken(token))
+	if err != nil {
+		ctx.Logger().Error("Failed to create token:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create token")
+	}
+	return ctx.JSON(http.StatusCreated, createdToken{
+		personalAccessToken: toPersonalAccessToken(t),
+		Token:               token,
+	})
+}
+
+func (h *handler) GetMyTokensHandler(ctx echo.Context) error {
+	username := ctx.Get(m.UsernameKey).(string)
+	tokens, err := h.repo.GetTokens(username)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve tokens:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve tokens")
+	}
+	result := make([]personalAccessToken, len(tokens))
+	for i := range tokens {
+		result[i] = toPersonalAccessToken(&tokens[i])
+	}
+	return ctx.JSON(http.StatusOK, result)
+}
+
+func (h *handler) RevokeTokenHandler(ctx echo.Context) error {
+	ID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid ID")
+	}
+	username := ctx.Get(m.UsernameKey).(string)
+	if err := h.repo.RevokeToken(ID, username); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "token not found")
+		}
+		ctx.Logger().Error("Failed to revoke token:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to revoke token")
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
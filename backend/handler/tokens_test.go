@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+func TestTokenScopeChecker(t *testing.T) {
+	h := newTestHandler(t, &fakeRepo{})
+	next := h.TokenScopeChecker(func(c echo.Context) error { return c.NoContent(http.StatusNoContent) })
+
+	token := func(scopes ...domain.TokenScope) *m.Identity {
+		identity := &m.Identity{Username: "alice", Provider: "token", TokenID: uuid.New()}
+		for _, s := range scopes {
+			identity.Scopes = append(identity.Scopes, string(s))
+		}
+		return identity
+	}
+	session := &m.Identity{Username: "alice", Provider: "oidc"}
+
+	tests := []struct {
+		name     string
+		identity *m.Identity
+		method   string
+		path     string
+		wantCode int
+	}{
+		{"session can do anything", session, http.MethodPost, "/api/me/tokens", http.StatusNoContent},
+		{"read with read scope", token(domain.TokenScopeRead), http.MethodGet, "/api/messages", http.StatusNoContent},
+		{"read without read scope", token(domain.TokenScopePost), http.MethodGet, "/api/messages", http.StatusForbidden},
+		{"post with post scope", token(domain.TokenScopePost), http.MethodPost, "/api/messages", http.StatusNoContent},
+		{"post without post scope", token(domain.TokenScopeRead), http.MethodPost, "/api/messages", http.StatusForbidden},
+		{"react with react scope", token(domain.TokenScopeReact), http.MethodPost, "/api/messages/:id/reactions", http.StatusNoContent},
+		{"vote with react scope", token(domain.TokenScopeReact), http.MethodPost, "/api/messages/:id/poll/votes", http.StatusNoContent},
+		{"listing tokens", token(domain.TokenScopes...), http.MethodGet, "/api/me/tokens", http.StatusForbidden},
+		{"creating tokens", token(domain.TokenScopes...), http.MethodPost, "/api/me/tokens", http.StatusForbidden},
+		{"other writes", token(domain.TokenScopes...), http.MethodPatch, "/api/me", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := newTestContext(tt.method, "/", tt.identity.Username)
+			c.SetPath(tt.path)
+			c.Set(m.IdentityKey, tt.identity)
+			err := next(c)
+			code := rec.Code
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				code = httpErr.Code
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestTokenIDOf(t *testing.T) {
+	tokenID := uuid.New()
+	tests := []struct {
+		name     string
+		identity *m.Identity
+		want     uuid.UUID
+	}{
+		{"not authenticated", nil, uuid.Nil},
+		{"session", &m.Identity{Username: "alice", Provider: "oidc"}, uuid.Nil},
+		{"token", &m.Identity{Username: "alice", Provider: "token", TokenID: tokenID}, tokenID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestContext(http.MethodPost, "/", "")
+			if tt.identity != nil {
+				c.Set(m.IdentityKey, tt.identity)
+			}
+			if got := tokenIDOf(c); got != tt.want {
+				t.Fatalf("tokenIDOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/traP-jp/h25s_09/domain"
+	m "github.com/traP-jp/h25s_09/handler/middleware"
+)
+
+type userStats struct {
+	PostCount         int64      `json:"postCount"`
+	ReplyCount        int64      `json:"replyCount"`
+	ReactionsReceived int64      `json:"reactionsReceived"`
+	ReactionsGiven    int64      `json:"reactionsGiven"`
+	AchievementsCount int64      `json:"achievementsCount"`
+	FirstPostAt       *time.Time `json:"firstPostAt"`
+	LastPostAt        *time.Time `json:"lastPostAt"`
+}
+
+type userProfile struct {
+	TraqID      string    `json:"traqId"`
+	DisplayName string    `json:"displayName"`
+	Bio         string    `json:"bio"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Stats       userStats `json:"stats"`
+}
+
+const (
+	// registeredUsersSize は users テーブルにあると覚えておくユーザーの数
+	registeredUsersSize = 10000
+	// registeredUsersTTL を過ぎたら users テーブルにあるかを確かめ直す
+	registeredUsersTTL = time.Hour
+)
+
+// userCache は users テーブルに存在することを確認済みのユーザーを、数と期間を限って覚えておく
+type userCache struct {
+	mu   sync.Mutex
+	size int
+	ttl  time.Duration
+	seen map[string]time.Time
+}
+
+func newUserCache(size int, ttl time.Duration) *userCache {
+	return &userCache{size: size, ttl: ttl, seen: map[string]time.Time{}}
+}
+
+func (c *userCache) contains(username string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seenAt, ok := c.seen[username]
+	return ok && now.Sub(seenAt) < c.ttl
+}
+
+func (c *userCache) add(username string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.seen) >= c.size {
+		for name, seenAt := range c.seen {
+			if now.Sub(seenAt) >= c.ttl {
+				delete(c.seen, name)
+			}
+		}
+		// 期限切れがなければ全て忘れる。確かめ直すだけなので困らない
+		if len(c.seen) >= c.size {
+			clear(c.seen)
+		}
+	}
+	c.seen[username] = now
+}
+
+// UserRegisterer はリクエストしたユーザーが users テーブルになければ作成する
+// UsernameProvider の後に使う
+func (h *handler) UserRegisterer(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		username, ok := c.Get(m.UsernameKey).(string)
+		if ok && username != "" {
+			now := time.Now()
+			if !h.registeredUsers.contains(username, now) {
+				if err := h.repo.EnsureUser(username); err != nil {
+					c.Logger().Error("Failed to register user:", username, err)
+				} else {
+					h.registeredUsers.add(username, now)
+				}
+			}
+		}
+		return next(c)
+	}
+}
+
+func (h *handler) GetUserProfileHandler(ctx echo.Context) error {
+	username := ctx.Param("name")
+	if username == "" {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	user, err := h.repo.GetUser(username)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, "user not found")
+		}
+		ctx.Logger().Error("Failed to retrieve user:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve user")
+	}
+	stats, err := h.repo.GetUserStats(username)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve user stats:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve user")
+	}
+	return ctx.JSON(http.StatusOK, toUserProfile(user, stats))
+}
+
+func (h *handler) PatchMeHandler(ctx echo.Context) error {
+	username := ctx.Get(m.UsernameKey).(string)
+
+	var reqBody struct {
+		DisplayName *string `json:"displayName"`
+		Bio         *string `json:"bio"`
+	}
+	if err := ctx.Bind(&reqBody); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	user, err := h.repo.GetUser(username)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		ctx.Logger().Error("Failed to retrieve user:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve user")
+	}
+	displayName, bio := "", ""
+	if user != nil {
+		displayName, bio = user.DisplayName, user.Bio
+	}
+	if reqBody.DisplayName != nil {
+		displayName = strings.TrimSpace(*reqBody.DisplayName)
+		if utf8.RuneCountInString(displayName) > domain.MaxDisplayNameLength {
+			return echo.NewHTTPError(http.StatusBadRequest, "display name is too long")
+		}
+	}
+	if reqBody.Bio != nil {
+		bio = strings.TrimSpace(*reqBody.Bio)
+		if utf8.RuneCountInString(bio) > domain.MaxBioLength {
+			return echo.NewHTTPError(http.StatusBadRequest, "bio is too long")
+		}
+	}
+
+	user, err = h.repo.UpdateUserProfile(username, displayName, bio)
+	if err != nil {
+		ctx.Logger().Error("Failed to update user profile:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update profile")
+	}
+	stats, err := h.repo.GetUserStats(username)
+	if err != nil {
+		ctx.Logger().Error("Failed to retrieve user stats:", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retrieve user")
+	}
+	return ctx.JSON(http.StatusOK, toUserProfile(user, stats))
+}
+
+func toUserProfile(user *domain.User, stats *domain.UserStats) userProfile {
+	return userProfile{
+		TraqID:      user.Username,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		CreatedAt:   user.CreatedAt,
+		Stats: userStats{
+			PostCount:         stats.PostCount,
+			ReplyCount:        stats.ReplyCount,
+			ReactionsReceived: stats.ReactionsReceived,
+			ReactionsGiven:    stats.ReactionsGiven,
+			AchievementsCount: stats.AchievementsCount,
+			FirstPostAt:       stats.FirstPostAt,
+			LastPostAt:        stats.LastPostAt,
+		},
+	}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestUserCache(t *testing.T) {
+	start := time.Now()
+	cache := newUserCache(2, time.Minute)
+	cache.add("alice", start)
+	cache.add("bob", start.Add(30*time.Second))
+
+	tests := []struct {
+		name     string
+		username string
+		now      time.Time
+		want     bool
+	}{
+		{"known", "alice", start.Add(time.Second), true},
+		{"unknown", "carol", start, false},
+		{"expired", "alice", start.Add(time.Minute), false},
+		{"not expired yet", "bob", start.Add(time.Minute), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cache.contains(tt.username, tt.now); got != tt.want {
+				t.Fatalf("contains(%q) = %v, want %v", tt.username, got, tt.want)
+			}
+		})
+	}
+
+	// 満杯になったら期限切れの alice を捨てて carol を覚える
+	cache.add("carol", start.Add(time.Minute))
+	if len(cache.seen) != 2 || !cache.contains("bob", start.Add(time.Minute)) || !cache.contains("carol", start.Add(time.Minute)) {
+		t.Fatalf("cache after eviction = %v", cache.seen)
+	}
+	// 期限切れがなければ全て忘れる
+	cache.add("dave", start.Add(time.Minute))
+	if len(cache.seen) != 1 || !cache.contains("dave", start.Add(time.Minute)) {
+		t.Fatalf("cache after reset = %v", cache.seen)
+	}
+}
+
+type userRegistererRepo struct {
+	fakeRepo
+	ensured []string
+}
+
+func (r *userRegistererRepo) EnsureUser(username string) error {
+	r.ensured = append(r.ensured, username)
+	return nil
+}
+
+func TestUserRegistererRegistersOnce(t *testing.T) {
+	repo := &userRegistererRepo{}
+	h := newTestHandler(t, repo)
+	next := h.UserRegisterer(func(c echo.Context) error { return nil })
+	for _, username := range []string{"alice", "alice", "bob", ""} {
+		c, _ := newTestContext(http.MethodGet, "/", username)
+		if err := next(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(repo.ensured) != 2 || repo.ensured[0] != "alice" || repo.ensured[1] != "bob" {
+		t.Fatalf("EnsureUser calls = %v, want [alice bob]", repo.ensured)
+	}
+}
@@ -0,0 +1,220 @@
+// imagefx は画像のデコード・加工・エンコードを行う
+//
+// JPEG, PNG, GIF(アニメーションを含む), WebP を読み込める。
+// WebPのエンコーダはないので、WebPはPNGかJPEGとして書き出す。
+package imagefx
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatGIF  = "gif"
+	FormatWebP = "webp"
+)
+
+// MaxPixels はデコードする画像の幅×高さの上限
+// 小さなファイルでも展開すると巨大になる画像でメモリを使い果たさないようにする
+const MaxPixels = 25_000_000
+
+// MaxTotalPixels は GIF の画面の幅×高さ×フレーム数の上限
+// 小さな GIF でも同じ大きさのフレームを大量に並べると、全フレームを展開したときに巨大になる
+// フレームはパレット画像なので1画素1バイトで、加工するときも RGBA にするのは1フレームずつ
+const MaxTotalPixels = 4 * MaxPixels
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrTooLarge          = errors.New("image dimensions are too large")
+)
+
+// Image はデコードされた画像で、GIFアニメーションの場合は複数のフレームを持つ
+type Image struct {
+	Frames []image.Image
+	Format string
+	Width  int
+	Height int
+	// JPEGで書き出すときの品質(1-100)。0ならjpeg.DefaultQuality
+	Quality int
+
+	// GIFのフレーム情報
+	delays    []int
+	disposal  []byte
+	loopCount int
+	bgIndex   byte
+}
+
+// Transform は画像を加工する。GIFの場合は全フレームに適用される
+type Transform func(img *Image)
+
+// Decode は画像を読み込む。幅×高さが MaxPixels を超える画像と、
+// 幅×高さ×フレーム数が MaxTotalPixels を超える GIF は展開せずに ErrTooLarge を返す
+func Decode(r io.Reader) (*Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	// GIFの各フレームは画面の大きさを超えないことをデコーダーが確かめる
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	if format == FormatGIF {
+		if int64(config.Width)*int64(config.Height)*int64(gifFrameCount(data)) > MaxTotalPixels {
+			return nil, ErrTooLarge
+		}
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		frames := make([]image.Image, len(g.Image))
+		for i, f := range g.Image {
+			frames[i] = f
+		}
+		return &Image{
+			Frames:    frames,
+			Format:    FormatGIF,
+			Width:     g.Config.Width,
+			Height:    g.Config.Height,
+			delays:    g.Delay,
+			disposal:  g.Disposal,
+			loopCount: g.LoopCount,
+			bgIndex:   g.BackgroundIndex,
+		}, nil
+	}
+
+	switch format {
+	case FormatJPEG, FormatPNG, FormatWebP:
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	return &Image{
+		Frames: []image.Image{img},
+		Format: format,
+		Width:  b.Dx(),
+		Height: b.Dy(),
+	}, nil
+}
+
+// gifFrameCount は GIF を展開せずにフレーム(イメージ記述子)の数を数える
+// 壊れたデータはそこまでに数えた数を返し、エラーにするのは gif.DecodeAll に任せる
+func gifFrameCount(data []byte) int {
+	// ヘッダー(6) と論理画面記述子(7) の後に、あればグローバルカラーテーブルが続く
+	p := 6 + 7
+	if len(data) < p {
+		return 0
+	}
+	if flags := data[10]; flags&0x80 != 0 {
+		p += 3 << (flags&0x07 + 1)
+	}
+	frames := 0
+	for p < len(data) {
+		switch data[p] {
+		case 0x21: // 拡張ブロック: 導入子とラベルの後にサブブロックが続く
+			p += 2
+		case 0x2c: // イメージ記述子: 記述子(10) と、あればローカルカラーテーブル、LZW の最小符号長の後にサブブロックが続く
+			frames++
+			if p+10 > len(data) {
+				return frames
+			}
+			if flags := data[p+9]; flags&0x80 != 0 {
+				p += 3 << (flags&0x07 + 1)
+			}
+			p += 10 + 1
+		default: // 0x3b は終端。それ以外は壊れている
+			return frames
+		}
+		for p < len(data) && data[p] != 0 {
+			p += int(data[p]) + 1
+		}
+		p++ // サブブロックの終端
+	}
+	return frames
+}
+
+func (img *Image) Apply(transforms ...Transform) {
+	for _, t := range transforms {
+		t(img)
+	}
+}
+
+// Encode は img を書き出し、そのMIMEタイプを返す
+func (img *Image) Encode(w io.Writer) (string, error) {
+	switch img.Format {
+	case FormatGIF:
+		return "image/gif", img.encodeGIF(w)
+	case FormatJPEG:
+		quality := img.Quality
+		if quality == 0 {
+			quality = jpeg.DefaultQuality
+		}
+		return "image/jpeg", jpeg.Encode(w, img.Frames[0], &jpeg.Options{Quality: quality})
+	case FormatPNG, FormatWebP:
+		return "image/png", png.Encode(w, img.Frames[0])
+	default:
+		return "", ErrUnsupportedFormat
+	}
+}
+
+func (img *Image) encodeGIF(w io.Writer) error {
+	g := &gif.GIF{
+		Image:           make([]*image.Paletted, len(img.Frames)),
+		Delay:           img.delays,
+		Disposal:        img.disposal,
+		LoopCount:       img.loopCount,
+		BackgroundIndex: img.bgIndex,
+		Config:          image.Config{Width: img.Width, Height: img.Height},
+	}
+	for i, f := range img.Frames {
+		g.Image[i] = toPaletted(f)
+	}
+	if len(g.Image) > 0 {
+		g.Config.ColorModel = g.Image[0].Palette
+	}
+	return gif.EncodeAll(w, g)
+}
+
+// mapFrames は各フレームを f の結果で置き換える。GIFのフレームは元のパレットを引き継ぐ
+func (img *Image) mapFrames(f func(frame image.Image) draw.Image) {
+	for i, frame := range img.Frames {
+		out := f(frame)
+		if p, ok := frame.(*image.Paletted); ok {
+			img.Frames[i] = withPalette(out, p.Palette)
+		} else {
+			img.Frames[i] = out
+		}
+	}
+}
+
+func toPaletted(f image.Image) *image.Paletted {
+	if p, ok := f.(*image.Paletted); ok {
+		return p
+	}
+	return withPalette(f, palettePlan9WithTransparent)
+}
+
+func withPalette(src image.Image, p color.Palette) *image.Paletted {
+	dst := image.NewPaletted(src.Bounds(), p)
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+	return dst
+}
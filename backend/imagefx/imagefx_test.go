@@ -0,0 +1,251 @@
+package imagefx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"slices"
+	"testing"
+)
+
+// pngWithSize は IHDR の幅と高さだけを書き換えた PNG を返す
+// 画素データは 1x1 のままなので、DecodeConfig より先に進むとデコードに失敗する
+func pngWithSize(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	// シグネチャ(8) + 長さ(4) の後に "IHDR" と幅・高さが続く
+	ihdr := data[12 : 12+4+13]
+	binary.BigEndian.PutUint32(ihdr[4:], width)
+	binary.BigEndian.PutUint32(ihdr[8:], height)
+	binary.BigEndian.PutUint32(data[12+4+13:], crc32.ChecksumIEEE(ihdr))
+	return data
+}
+
+func TestDecodeRejectsTooManyPixels(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height uint32
+		wantTooLarge  bool
+	}{
+		{"small", 1, 1, false},
+		{"at the limit", MaxPixels / 5000, 5000, false},
+		{"over the limit", MaxPixels/5000 + 1, 5000, true},
+		{"huge", 100_000, 100_000, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(bytes.NewReader(pngWithSize(t, tt.width, tt.height)))
+			if got := errors.Is(err, ErrTooLarge); got != tt.wantTooLarge {
+				t.Fatalf("Decode() error = %v, want ErrTooLarge: %v", err, tt.wantTooLarge)
+			}
+		})
+	}
+}
+
+func TestDecodeAndEncode(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 3))); err != nil {
+		t.Fatal(err)
+	}
+	img, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Format != FormatPNG || img.Width != 4 || img.Height != 3 {
+		t.Fatalf("Decode() = %s %dx%d, want png 4x3", img.Format, img.Width, img.Height)
+	}
+	var out bytes.Buffer
+	mime, err := img.Encode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mime != "image/png" {
+		t.Fatalf("Encode() mime = %q, want image/png", mime)
+	}
+}
+
+// animatedGIF は width×height の画面に frame×frame のフレームを n 枚並べた GIF を返す
+// フレーム i は i 番目の色で塗られ、表示時間は (i+1)*10 になる
+func animatedGIF(t *testing.T, width, height, frame, n int) []byte {
+	t.Helper()
+	p := color.Palette{color.Transparent, color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255}, color.RGBA{0, 0, 255, 255}}
+	g := &gif.GIF{LoopCount: 3, Config: image.Config{Width: width, Height: height, ColorModel: p}}
+	for i := range n {
+		f := image.NewPaletted(image.Rect(0, 0, frame, frame), p)
+		for j := range f.Pix {
+			f.Pix[j] = uint8(i%3 + 1)
+		}
+		g.Image = append(g.Image, f)
+		g.Delay = append(g.Delay, (i+1)*10)
+		g.Disposal = append(g.Disposal, gif.DisposalBackground)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeRejectsTooManyGIFFrames(t *testing.T) {
+	// 画面は MaxPixels ちょうどで、フレームは 1x1 なのでファイルは小さい
+	width, height := MaxPixels/5000, 5000
+	perCanvas := MaxTotalPixels / MaxPixels
+	tests := []struct {
+		name         string
+		frames       int
+		wantTooLarge bool
+	}{
+		{"at the limit", perCanvas, false},
+		{"over the limit", perCanvas + 1, true},
+		{"many frames", 1000, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := animatedGIF(t, width, height, 1, tt.frames)
+			if got := gifFrameCount(data); got != tt.frames {
+				t.Fatalf("gifFrameCount() = %d, want %d", got, tt.frames)
+			}
+			img, err := Decode(bytes.NewReader(data))
+			if got := errors.Is(err, ErrTooLarge); got != tt.wantTooLarge {
+				t.Fatalf("Decode() error = %v, want ErrTooLarge: %v", err, tt.wantTooLarge)
+			}
+			if err == nil && len(img.Frames) != tt.frames {
+				t.Fatalf("Decode() frames = %d, want %d", len(img.Frames), tt.frames)
+			}
+		})
+	}
+}
+
+func TestGIFFrameCountWithColorTablesAndExtensions(t *testing.T) {
+	// 各フレームにローカルカラーテーブル、グラフィック制御拡張、ループ回数のアプリケーション拡張が付く
+	g := &gif.GIF{LoopCount: 1}
+	for i := range 3 {
+		p := color.Palette{color.Black, color.Gray{uint8(i * 50)}}
+		g.Image = append(g.Image, image.NewPaletted(image.Rect(0, 0, 300, 300), p))
+		g.Delay = append(g.Delay, 5)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if got := gifFrameCount(data); got != 3 {
+		t.Fatalf("gifFrameCount() = %d, want 3", got)
+	}
+	if got := gifFrameCount(data[:len(data)/2]); got > 3 {
+		t.Fatalf("gifFrameCount(truncated) = %d, want at most 3", got)
+	}
+	if got := gifFrameCount(data[:5]); got != 0 {
+		t.Fatalf("gifFrameCount(header only) = %d, want 0", got)
+	}
+}
+
+func TestTransformsKeepGIFAnimation(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform Transform
+		// check は加工後の最初のフレームを調べる
+		check func(t *testing.T, frame *image.Paletted)
+	}{
+		{"HalfLoaded", HalfLoaded(), func(t *testing.T, frame *image.Paletted) {
+			if _, _, _, a := frame.At(0, 0).RGBA(); a == 0 {
+				t.Error("upper half is transparent")
+			}
+			if _, _, _, a := frame.At(0, 15).RGBA(); a != 0 {
+				t.Error("lower half is not transparent")
+			}
+		}},
+		{"Pixelate", Pixelate(4), nil},
+		{"LowQuality", LowQuality(5), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Decode(bytes.NewReader(animatedGIF(t, 16, 16, 16, 3)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			img.Apply(tt.transform)
+			var buf bytes.Buffer
+			mime, err := img.Encode(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mime != "image/gif" {
+				t.Fatalf("Encode() mime = %q, want image/gif", mime)
+			}
+			g, err := gif.DecodeAll(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(g.Image) != 3 {
+				t.Fatalf("frames = %d, want 3", len(g.Image))
+			}
+			if want := []int{10, 20, 30}; !slices.Equal(g.Delay, want) {
+				t.Fatalf("delays = %v, want %v", g.Delay, want)
+			}
+			if g.LoopCount != 3 || g.Config.Width != 16 || g.Config.Height != 16 {
+				t.Fatalf("loop count, size = %d, %dx%d, want 3, 16x16", g.LoopCount, g.Config.Width, g.Config.Height)
+			}
+			// フレームごとの色が残っている
+			if r, _, _, _ := g.Image[0].At(0, 0).RGBA(); r>>8 != 255 {
+				t.Errorf("first frame color = %v, want red", g.Image[0].At(0, 0))
+			}
+			if _, gr, _, _ := g.Image[1].At(0, 0).RGBA(); gr>>8 != 255 {
+				t.Errorf("second frame color = %v, want green", g.Image[1].At(0, 0))
+			}
+			if tt.check != nil {
+				tt.check(t, g.Image[0])
+			}
+		})
+	}
+}
+
+func TestDecodeWebP(t *testing.T) {
+	tests := []struct {
+		file          string
+		width, height int
+	}{
+		{"testdata/lossless.webp", 75, 100},
+		{"testdata/lossy.webp", 150, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := os.Open(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			img, err := Decode(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if img.Format != FormatWebP || img.Width != tt.width || img.Height != tt.height {
+				t.Fatalf("Decode() = %s %dx%d, want webp %dx%d", img.Format, img.Width, img.Height, tt.width, tt.height)
+			}
+
+			// WebP のエンコーダはないので、そのままなら PNG、LowQuality なら JPEG で書き出す
+			var buf bytes.Buffer
+			if mime, err := img.Encode(&buf); err != nil || mime != "image/png" {
+				t.Fatalf("Encode() = %q, %v, want image/png", mime, err)
+			}
+			img.Apply(LowQuality(5))
+			buf.Reset()
+			if mime, err := img.Encode(&buf); err != nil || mime != "image/jpeg" {
+				t.Fatalf("Encode() after LowQuality = %q, %v, want image/jpeg", mime, err)
+			}
+			if _, format, err := image.DecodeConfig(&buf); err != nil || format != FormatJPEG {
+				t.Fatalf("encoded format = %q, %v, want jpeg", format, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package imagefx
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+)
+
+var palettePlan9WithTransparent = append(color.Palette{color.Transparent}, palette.Plan9[:255]...)
+
+// HalfLoaded は画像の下半分を塗りつぶし、読み込みが途中で止まったように見せる
+func HalfLoaded() Transform {
+	return func(img *Image) {
+		cut := img.Height / 2
+		img.mapFrames(func(frame image.Image) draw.Image {
+			dst := cloneRGBA(frame)
+			b := dst.Bounds()
+			if cut < b.Max.Y {
+				lower := image.Rect(b.Min.X, max(cut, b.Min.Y), b.Max.X, b.Max.Y)
+				draw.Draw(dst, lower, image.Transparent, image.Point{}, draw.Src)
+			}
+			return dst
+		})
+	}
+}
+
+// Pixelate は画像を block ピクセル四方のモザイクにする
+func Pixelate(block int) Transform {
+	return func(img *Image) {
+		if block <= 1 {
+			return
+		}
+		img.mapFrames(func(frame image.Image) draw.Image {
+			src := cloneRGBA(frame)
+			dst := image.NewRGBA(src.Bounds())
+			b := src.Bounds()
+			// フレームの位置によらず同じ格子になるよう、画像全体の座標で区切る
+			for y := b.Min.Y - b.Min.Y%block; y < b.Max.Y; y += block {
+				for x := b.Min.X - b.Min.X%block; x < b.Max.X; x += block {
+					cell := image.Rect(x, y, x+block, y+block).Intersect(b)
+					draw.Draw(dst, cell, image.NewUniform(src.At(cell.Min.X, cell.Min.Y)), image.Point{}, draw.Src)
+				}
+			}
+			return dst
+		})
+	}
+}
+
+// LowQuality は画質を落とす。静止画は低品質のJPEGとして書き出し、
+// JPEGにできないGIFはアニメーションを保ったままモザイクをかける
+func LowQuality(quality int) Transform {
+	return func(img *Image) {
+		if img.Format == FormatGIF {
+			Pixelate(8)(img)
+			return
+		}
+		img.Format = FormatJPEG
+		img.Quality = quality
+	}
+}
+
+func cloneRGBA(src image.Image) *image.RGBA {
+	dst := image.NewRGBA(src.Bounds())
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+	return dst
+}
@@ -11,6 +11,8 @@ import (
 type AchievementsRepository interface {
 	GetUserAchievements(username string) ([]domain.UserAchievement, error)
 	InsertUserAchievement(username string, achievementName string) (*domain.UserAchievement, error)
+	// DeleteUserAchievement は実績を持っていなければ domain.ErrNotFound を返す
+	DeleteUserAchievement(username string, achievementName string) error
 }
 
 type userAchievement struct {
@@ -62,3 +64,18 @@ func (r *repositoryImpl) InsertUserAchievement(username string, achievementName
 
 	return &domainAchievement, nil
 }
+
+func (r *repositoryImpl) DeleteUserAchievement(username string, achievementName string) error {
+	res, err := r.db.Exec("DELETE FROM achievements WHERE name = ? AND username = ?", achievementName, username)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
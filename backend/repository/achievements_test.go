@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+func TestUserAchievements(t *testing.T) {
+	r, _ := newTestRepository(t)
+
+	achievement, err := r.InsertUserAchievement("alice", "ぐるぐる")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if achievement.AchievementName != "ぐるぐる" || achievement.Username != "alice" {
+		t.Fatalf("InsertUserAchievement() = %+v", achievement)
+	}
+	achievements, err := r.GetUserAchievements("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(achievements) != 1 || achievements[0].AchievementName != "ぐるぐる" {
+		t.Fatalf("GetUserAchievements() = %+v", achievements)
+	}
+
+	if err := r.DeleteUserAchievement("bob", "ぐるぐる"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("DeleteUserAchievement(other user) error = %v, want ErrNotFound", err)
+	}
+	if err := r.DeleteUserAchievement("alice", "ぐるぐる"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteUserAchievement("alice", "ぐるぐる"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("DeleteUserAchievement(again) error = %v, want ErrNotFound", err)
+	}
+	if achievements, err := r.GetUserAchievements("alice"); err != nil || len(achievements) != 0 {
+		t.Fatalf("GetUserAchievements() after revoke = %+v, %v", achievements, err)
+	}
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+type BookmarkRepository interface {
+	// InsertBookmark は既にブックマークしていれば domain.ErrConflict を返す
+	InsertBookmark(messageID uuid.UUID, username string) error
+	// DeleteBookmark はブックマークしていなければ domain.ErrNotFound を返す
+	DeleteBookmark(messageID uuid.UUID, username string) error
+	IsBookmarked(messageID uuid.UUID, username string) (bool, error)
+	// GetBookmarkedMessages は cursor より古いブックマークを新しい順に最大 limit 件返す
+	GetBookmarkedMessages(username string, cursor domain.BookmarkCursor, limit int64) ([]domain.BookmarkedMessage, error)
+}
+
+type repoBookmarkedMessage struct {
+	Message
+	BookmarkedAt time.Time `db:"bookmarked_at"`
+}
+
+func (r *repositoryImpl) InsertBookmark(messageID uuid.UUID, username string) error {
+	res, err := r.db.Exec("INSERT IGNORE INTO bookmarks (message_id, username) VALUES (?, ?)", messageID, username)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrConflict
+	}
+	return nil
+}
+
+func (r *repositoryImpl) DeleteBookmark(messageID uuid.UUID, username string) error {
+	res, err := r.db.Exec("DELETE FROM bookmarks WHERE message_id = ? AND username = ?", messageID, username)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repositoryImpl) IsBookmarked(messageID uuid.UUID, username string) (bool, error) {
+	var exists bool
+	err := r.db.Get(&exists, "SELECT TRUE FROM bookmarks WHERE message_id = ? AND username = ?", messageID, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return exists, nil
+}
+
+func (r *repositoryImpl) GetBookmarkedMessages(username string, cursor domain.BookmarkCursor, limit int64) ([]domain.BookmarkedMessage, error) {
+	query := `SELECT m.id, m.author, m.message, m.replies_to, m.quote_of, m.status, m.publish_at, m.token_id, m.created_at, m.updated_at, b.created_at AS bookmarked_at
+		FROM bookmarks b JOIN messages m ON m.id = b.message_id
+		WHERE b.username = ? AND m.status = ?`
+	args := []any{username, domain.MessageStatusPublished}
+	if !cursor.CreatedAt.IsZero() {
+		// 同じ日時のブックマークはメッセージIDの降順で並べる
+		query += " AND (b.created_at < ? OR (b.created_at = ? AND b.message_id < ?))"
+		args = append(args, cursor.CreatedAt, cursor.CreatedAt, cursor.MessageID)
+	}
+	query += " ORDER BY b.created_at DESC, b.message_id DESC LIMIT ?"
+	args = append(args, limit)
+
+	var rows []repoBookmarkedMessage
+	if err := r.db.Select(&rows, query, args...); err != nil {
+		return nil, err
+	}
+	result := make([]domain.BookmarkedMessage, len(rows))
+	for i := range rows {
+		result[i] = domain.BookmarkedMessage{
+			Message:      rows[i].toDomain(),
+			BookmarkedAt: rows[i].BookmarkedAt,
+		}
+	}
+	return result, nil
+}
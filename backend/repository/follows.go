@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+type FollowRepository interface {
+	// InsertFollow は既にフォローしていれば domain.ErrConflict を返す
+	InsertFollow(follower, followee string) error
+	// DeleteFollow はフォローしていなければ domain.ErrNotFound を返す
+	DeleteFollow(follower, followee string) error
+	IsFollowing(follower, followee string) (bool, error)
+	GetFollowers(username string, limit, offset int64) ([]domain.Follow, error)
+	GetFollowing(username string, limit, offset int64) ([]domain.Follow, error)
+	GetFollowCounts(username string) (*domain.FollowCounts, error)
+	// GetTimeline は username とそのフォローしているユーザーのメッセージを新しい順に返す
+	// username がミュート・ブロックしているユーザーのメッセージは含めない
+	// リポストされたメッセージも含み、同じメッセージは最も新しいもの1件だけになる
+	GetTimeline(username string, limit, offset int64, includeReplies bool) ([]domain.TimelineEntry, error)
+}
+
+type repoFollow struct {
+	Follower  string    `db:"follower"`
+	Followee  string    `db:"followee"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (r *repositoryImpl) InsertFollow(follower, followee string) error {
+	res, err := r.db.Exec("INSERT IGNORE INTO follows (follower, followee) VALUES (?, ?)", follower, followee)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrConflict
+	}
+	return nil
+}
+
+func (r *repositoryImpl) DeleteFollow(follower, followee string) error {
+	res, err := r.db.Exec("DELETE FROM follows WHERE follower = ? AND followee = ?", follower, followee)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repositoryImpl) IsFollowing(follower, followee string) (bool, error) {
+	var exists bool
+	err := r.db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM follows WHERE follower = ? AND followee = ?)", follower, followee)
+	return exists, err
+}
+
+func (r *repositoryImpl) GetFollowers(username string, limit, offset int64) ([]domain.Follow, error) {
+	var follows []repoFollow
+	err := r.db.Select(&follows, "SELECT follower, followee, created_at FROM follows WHERE followee = ? ORDER BY created_at DESC LIMIT ? OFFSET ?", username, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return toDomainFollows(follows), nil
+}
+
+func (r *repositoryImpl) GetFollowing(username string, limit, offset int64) ([]domain.Follow, error) {
+	var follows []repoFollow
+	err := r.db.Select(&follows, "SELECT follower, followee, created_at FROM follows WHERE follower = ? ORDER BY created_at DESC LIMIT ? OFFSET ?", username, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return toDomainFollows(follows), nil
+}
+
+func toDomainFollows(follows []repoFollow) []domain.Follow {
+	result := make([]domain.Follow, len(follows))
+	for i, f := range follows {
+		result[i] = domain.Follow{
+			Follower:  f.Follower,
+			Followee:  f.Followee,
+			CreatedAt: f.CreatedAt,
+		}
+	}
+	return result
+}
+
+func (r *repositoryImpl) GetFollowCounts(username string) (*domain.FollowCounts, error) {
+	var counts struct {
+		Followers int64 `db:"followers"`
+		Following int64 `db:"following"`
+	}
+	err := r.db.Get(&counts, `SELECT
+		(SELECT COUNT(*) FROM follows WHERE followee = ?) AS followers,
+		(SELECT COUNT(*) FROM follows WHERE follower = ?) AS following`, username, username)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.FollowCounts{
+		Followers: counts.Followers,
+		Following: counts.Following,
+	}, nil
+}
+
+func (r *repositoryImpl) GetTimeline(username string, limit, offset int64, includeReplies bool) ([]domain.TimelineEntry, error) {
+	// 自分とフォローしているユーザーの投稿とリポストを時刻順に並べる
+	// 同じメッセージが複数回出てこないよう、メッセージごとに最も新しいものだけを残す
+	// 予約投稿は公開されるまで含めない。ミュート・ブロックしているユーザーのメッセージも含めない
+	replyFilter := " AND m.status = ? AND m.author NOT IN (" + hiddenAuthorsQuery + ")"
+	args := []any{username, username, domain.MessageStatusPublished, username, username, username}
+	if !includeReplies {
+		replyFilter += " AND m.replies_to = ?"
+		args = append(args, uuid.Nil)
+	}
+	args = append(args, username, username, domain.MessageStatusPublished, username, username, username)
+	if !includeReplies {
+		args = append(args, uuid.Nil)
+	}
+	args = append(args, limit, offset)
+
+	query := `SELECT id, author, message, replies_to, quote_of, status, publish_at, token_id, created_at, updated_at, reposted_by, reposted_at FROM (
+			SELECT t.*, ROW_NUMBER() OVER (PARTITION BY t.id ORDER BY t.sort_at DESC) AS rn FROM (
+				SELECT m.id, m.author, m.message, m.replies_to, m.quote_of, m.status, m.publish_at, m.token_id, m.created_at, m.updated_at,
+					'' AS reposted_by, NULL AS reposted_at, m.created_at AS sort_at
+				FROM messages m
+				WHERE (m.author = ? OR m.author IN (SELECT followee FROM follows WHERE follower = ?))` + replyFilter + `
+				UNION ALL
+				SELECT m.id, m.author, m.message, m.replies_to, m.quote_of, m.status, m.publish_at, m.token_id, m.created_at, m.updated_at,
+					rp.username AS reposted_by, rp.created_at AS reposted_at, rp.created_at AS sort_at
+				FROM reposts rp JOIN messages m ON m.id = rp.message_id
+				WHERE (rp.username = ? OR rp.username IN (SELECT followee FROM follows WHERE follower = ?))` + replyFilter + `
+			) t
+		) ranked
+		WHERE rn = 1
+		ORDER BY COALESCE(reposted_at, created_at) DESC LIMIT ? OFFSET ?`
+
+	var rows []struct {
+		Message
+		RepostedBy string       `db:"reposted_by"`
+		RepostedAt sql.NullTime `db:"reposted_at"`
+	}
+	if err := r.db.Select(&rows, query, args...); err != nil {
+		return nil, err
+	}
+
+	entries := make([]domain.TimelineEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = domain.TimelineEntry{
+			Message:    row.toDomain(),
+			RepostedBy: row.RepostedBy,
+			RepostedAt: row.RepostedAt.Time,
+		}
+	}
+	return entries, nil
+}
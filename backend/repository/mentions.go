@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+type MessageMentionRepository interface {
+	GetMentionsByMessageID(messageID uuid.UUID) ([]domain.Mention, error)
+	// GetMessagesMentioning は username がメンションされたメッセージを新しい順に返す
+	// username がミュート・ブロックしているユーザーと、username をブロックしているユーザーのメッセージは含めない
+	GetMessagesMentioning(username string, limit, offset int64) ([]domain.Message, error)
+}
+
+type repoMention struct {
+	Username string `db:"username"`
+	Offset   int    `db:"offset"`
+	Length   int    `db:"length"`
+}
+
+func (r *repositoryImpl) GetMentionsByMessageID(messageID uuid.UUID) ([]domain.Mention, error) {
+	var mentions []repoMention
+	err := r.db.Select(&mentions, "SELECT username, `offset`, length FROM message_mentions WHERE message_id = ? ORDER BY `offset`", messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.Mention, len(mentions))
+	for i, m := range mentions {
+		result[i] = domain.Mention{
+			Username: m.Username,
+			Offset:   m.Offset,
+			Length:   m.Length,
+		}
+	}
+	return result, nil
+}
+
+func (r *repositoryImpl) GetMessagesMentioning(username string, limit, offset int64) ([]domain.Message, error) {
+	var messages []Message
+	err := r.db.Select(&messages, `SELECT id, author, message, replies_to, quote_of, status, publish_at, token_id, created_at, updated_at FROM messages
+		WHERE id IN (SELECT message_id FROM message_mentions WHERE username = ?) AND status = ? AND author NOT IN (`+hiddenAuthorsQuery+`)
+		ORDER BY created_at DESC LIMIT ? OFFSET ?`, username, domain.MessageStatusPublished, username, username, username, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	domainMessages := make([]domain.Message, len(messages))
+	for i, msg := range messages {
+		domainMessages[i] = msg.toDomain()
+	}
+	return domainMessages, nil
+}
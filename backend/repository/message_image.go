@@ -6,26 +6,50 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/traP-jp/h25s_09/domain"
 )
 
 type MessageImageRepository interface {
 	GetMessageImage(imageID uuid.UUID) (*domain.MessageImage, error)
-	CreateMessageImage(messageID uuid.UUID, data []byte, mime string) (*domain.MessageImage, error)
+	GetImageBlob(hash string) (*domain.ImageBlob, error)
+	// AcquireImageBlob は hash のBlobの参照カウントを増やす。まだ登録されていなければ行を作り、upload でBlobを保存してから確定する
+	// upload の間は行をロックしているので、同じ画像を同時に投稿したリクエストは保存が終わるまで待つ
+	// upload に失敗したら何も登録しない
+	AcquireImageBlob(hash, blobKey, mime string, size int64, upload func() error) error
+	// ReleaseImageBlob は AcquireImageBlob で増やした参照カウントを減らし、参照がなくなったBlobを deleteBlob で消す
+	ReleaseImageBlob(hash string, deleteBlob func(key string)) error
+	// CreateMessageImage はメッセージに画像を添付する。hash のBlobの参照は先に AcquireImageBlob で増やしておく
+	CreateMessageImage(messageID uuid.UUID, hash string) (*domain.MessageImage, error)
+	// DeleteMessageImage は画像を削除して参照カウントを減らし、どこからも参照されなくなったBlobを deleteBlob で消す
+	DeleteMessageImage(imageID uuid.UUID, deleteBlob func(key string)) error
 	GetMessageImageIDByMessageID(messageID uuid.UUID) (uuid.UUID, error)
 }
 
 type repoMessageImage struct {
 	ID        uuid.UUID `db:"id"`
 	MessageID uuid.UUID `db:"message_id"`
-	Data      []byte    `db:"data"`
+	BlobKey   string    `db:"blob_key"`
+	Hash      string    `db:"content_hash"`
 	Mime      string    `db:"mime"`
+	Size      int64     `db:"size"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type repoImageBlob struct {
+	Hash      string    `db:"hash"`
+	BlobKey   string    `db:"blob_key"`
+	Mime      string    `db:"mime"`
+	Size      int64     `db:"size"`
+	RefCount  int64     `db:"ref_count"`
 	CreatedAt time.Time `db:"created_at"`
 }
 
 func (r *repositoryImpl) GetMessageImage(imageID uuid.UUID) (*domain.MessageImage, error) {
 	var img repoMessageImage
-	err := r.db.Get(&img, "SELECT * FROM message_images WHERE id=?", imageID)
+	err := r.db.Get(&img, `SELECT mi.id, mi.message_id, ib.blob_key, mi.content_hash, ib.mime, ib.size, mi.created_at
+		FROM message_images mi JOIN image_blobs ib ON ib.hash = mi.content_hash
+		WHERE mi.id=?`, imageID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, domain.ErrNotFound
@@ -36,27 +60,127 @@ func (r *repositoryImpl) GetMessageImage(imageID uuid.UUID) (*domain.MessageImag
 	return &domain.MessageImage{
 		ID:        img.ID,
 		MessageID: img.MessageID,
-		Data:      img.Data,
+		BlobKey:   img.BlobKey,
+		Hash:      img.Hash,
 		Mime:      img.Mime,
+		Size:      img.Size,
 		CreatedAt: img.CreatedAt,
 	}, nil
 }
 
-func (r *repositoryImpl) CreateMessageImage(messageID uuid.UUID, data []byte, mime string) (*domain.MessageImage, error) {
-	img := repoMessageImage{
-		ID:        uuid.Must(uuid.NewV7()),
-		MessageID: messageID,
-		Data:      data,
-		Mime:      mime,
+func (r *repositoryImpl) GetImageBlob(hash string) (*domain.ImageBlob, error) {
+	var blob repoImageBlob
+	err := r.db.Get(&blob, "SELECT hash, blob_key, mime, size, ref_count, created_at FROM image_blobs WHERE hash=?", hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &domain.ImageBlob{
+		Hash:      blob.Hash,
+		BlobKey:   blob.BlobKey,
+		Mime:      blob.Mime,
+		Size:      blob.Size,
+		RefCount:  blob.RefCount,
+		CreatedAt: blob.CreatedAt,
+	}, nil
+}
+
+func (r *repositoryImpl) AcquireImageBlob(hash, blobKey, mime string, size int64, upload func() error) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO image_blobs (hash, blob_key, mime, size, ref_count) VALUES (?, ?, ?, ?, 1)
+		ON DUPLICATE KEY UPDATE ref_count = ref_count + 1`, hash, blobKey, mime, size)
+	if err != nil {
+		return err
+	}
+	// 新しく行を作ったときは 1、既にある行の参照カウントを増やしたときは 2 になる
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 1 {
+		if err := upload(); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *repositoryImpl) ReleaseImageBlob(hash string, deleteBlob func(key string)) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := releaseImageBlob(tx, hash, deleteBlob); err != nil {
+		return err
 	}
-	res, err := r.db.NamedExec("INSERT INTO message_images (id, message_id, data, mime) VALUES (:id, :message_id, :data, :mime)", img)
+	return tx.Commit()
+}
+
+func (r *repositoryImpl) CreateMessageImage(messageID uuid.UUID, hash string) (*domain.MessageImage, error) {
+	imageID := uuid.Must(uuid.NewV7())
+	res, err := r.db.Exec("INSERT INTO message_images (id, message_id, content_hash) VALUES (?, ?, ?)", imageID, messageID, hash)
 	if err != nil {
 		return nil, err
 	}
 	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
 		return nil, errors.New("no rows affected")
 	}
-	return r.GetMessageImage(img.ID)
+	return r.GetMessageImage(imageID)
+}
+
+func (r *repositoryImpl) DeleteMessageImage(imageID uuid.UUID, deleteBlob func(key string)) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var hash string
+	err = tx.Get(&hash, "SELECT content_hash FROM message_images WHERE id=? FOR UPDATE", imageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrNotFound
+		}
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM message_images WHERE id=?", imageID); err != nil {
+		return err
+	}
+	if err := releaseImageBlob(tx, hash, deleteBlob); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// releaseImageBlob は tx の中で hash の参照カウントを減らし、0 になれば行を消してから deleteBlob を呼ぶ
+// 行のロックを持ったまま消すので、同じ画像を投稿しようとしている AcquireImageBlob は確定を待ってからBlobを保存し直す
+func releaseImageBlob(tx *sqlx.Tx, hash string, deleteBlob func(key string)) error {
+	var blob repoImageBlob
+	err := tx.Get(&blob, "SELECT hash, blob_key, mime, size, ref_count, created_at FROM image_blobs WHERE hash=? FOR UPDATE", hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrNotFound
+		}
+		return err
+	}
+	if blob.RefCount > 1 {
+		_, err := tx.Exec("UPDATE image_blobs SET ref_count = ref_count - 1 WHERE hash=?", hash)
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM image_blobs WHERE hash=?", hash); err != nil {
+		return err
+	}
+	deleteBlob(blob.BlobKey)
+	return nil
 }
 
 func (r *repositoryImpl) GetMessageImageIDByMessageID(messageID uuid.UUID) (uuid.UUID, error) {
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+func TestDeleteMessageImageReleasesBlob(t *testing.T) {
+	r, _ := newTestRepository(t)
+
+	const hash = "0000000000000000000000000000000000000000000000000000000000000001"
+	var imageIDs []uuid.UUID
+	for range 2 {
+		if err := r.AcquireImageBlob(hash, hash, "image/png", 5, func() error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+		msg, err := r.CreateMessage("alice", "image", uuid.Nil, uuid.Nil, time.Time{}, uuid.Nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := r.CreateMessageImage(msg.ID, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imageIDs = append(imageIDs, img.ID)
+	}
+
+	tests := []struct {
+		name         string
+		imageID      uuid.UUID
+		wantOrphan   string
+		wantRefCount int64 // 0 なら image_blobs の行は消えている
+	}{
+		{"decrement", imageIDs[0], "", 1},
+		{"decrement to zero", imageIDs[1], hash, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orphan := ""
+			err := r.DeleteMessageImage(tt.imageID, func(key string) { orphan = key })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if orphan != tt.wantOrphan {
+				t.Fatalf("orphan key = %q, want %q", orphan, tt.wantOrphan)
+			}
+			if _, err := r.GetMessageImage(tt.imageID); !errors.Is(err, domain.ErrNotFound) {
+				t.Fatalf("GetMessageImage after delete = %v, want domain.ErrNotFound", err)
+			}
+			blob, err := r.GetImageBlob(hash)
+			if tt.wantRefCount == 0 {
+				if !errors.Is(err, domain.ErrNotFound) {
+					t.Fatalf("GetImageBlob = %v, want domain.ErrNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if blob.RefCount != tt.wantRefCount {
+				t.Fatalf("ref_count = %d, want %d", blob.RefCount, tt.wantRefCount)
+			}
+		})
+	}
+
+	if err := r.DeleteMessageImage(imageIDs[0], func(string) {}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("deleting twice = %v, want domain.ErrNotFound", err)
+	}
+}
+
+func TestAcquireImageBlob(t *testing.T) {
+	r, _ := newTestRepository(t)
+
+	const hash = "0000000000000000000000000000000000000000000000000000000000000002"
+	uploadErr := errors.New("upload failed")
+	if err := r.AcquireImageBlob(hash, hash, "image/png", 5, func() error { return uploadErr }); !errors.Is(err, uploadErr) {
+		t.Fatalf("AcquireImageBlob with failing upload = %v, want %v", err, uploadErr)
+	}
+	if _, err := r.GetImageBlob(hash); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("GetImageBlob after failed upload = %v, want domain.ErrNotFound", err)
+	}
+
+	// 1つ目のアップロードが終わるまで、同じ画像の2つ目はアップロードも確定もしない
+	uploads := 0
+	second := make(chan error, 1)
+	err := r.AcquireImageBlob(hash, hash, "image/png", 5, func() error {
+		uploads++
+		go func() {
+			second <- r.AcquireImageBlob(hash, hash, "image/png", 5, func() error {
+				uploads++
+				return nil
+			})
+		}()
+		select {
+		case err := <-second:
+			t.Errorf("second AcquireImageBlob returned %v while the first was uploading", err)
+		case <-time.After(200 * time.Millisecond):
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-second; err != nil {
+		t.Fatal(err)
+	}
+	if uploads != 1 {
+		t.Fatalf("uploads = %d, want 1", uploads)
+	}
+	blob, err := r.GetImageBlob(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blob.RefCount != 2 {
+		t.Fatalf("ref_count = %d, want 2", blob.RefCount)
+	}
+
+	var deleted []string
+	for range 2 {
+		if err := r.ReleaseImageBlob(hash, func(key string) { deleted = append(deleted, key) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(deleted) != 1 || deleted[0] != hash {
+		t.Fatalf("deleted blobs = %v, want [%s]", deleted, hash)
+	}
+	if err := r.ReleaseImageBlob(hash, func(string) {}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("releasing twice = %v, want domain.ErrNotFound", err)
+	}
+}
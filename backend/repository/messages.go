@@ -6,41 +6,71 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 
 	"github.com/traP-jp/h25s_09/domain"
 )
 
 type MessageRepository interface {
-	CreateMessage(author, content string, parentID uuid.UUID) (*domain.Message, error)
+	// CreateMessage は返信でなければ parentID に、引用でなければ quoteOf に uuid.Nil を渡す
+	// publishAt がゼロ値でなければ予約投稿として保存し、その日時まで公開しない
+	// トークンで投稿したのでなければ tokenID に uuid.Nil を、投票を付けなければ poll に nil を渡す
+	CreateMessage(author, content string, parentID, quoteOf uuid.UUID, publishAt time.Time, tokenID uuid.UUID, poll *domain.NewPoll) (*domain.Message, error)
 	GetMessageByID(id uuid.UUID) (*domain.Message, error)
-	GetMessages(limit, offset int64, username string, includeReplies bool) ([]domain.Message, error)
+	// GetMessages は viewer がミュート・ブロックしているユーザーと viewer をブロックしているユーザーのメッセージを含めない
+	GetMessages(limit, offset int64, username string, includeReplies bool, viewer string) ([]domain.Message, error)
 	GetRepliesByMessageID(messageID uuid.UUID) ([]*domain.Message, error)
+	// SetMessageStatus は status が from のメッセージだけを to にする。そうでなければ domain.ErrNotFound を返す
+	SetMessageStatus(id uuid.UUID, from, to domain.MessageStatus) error
+	// SearchMessages は GetMessages と同じく query.Viewer に見せないユーザーのメッセージを含めない
+	SearchMessages(query domain.MessageSearchQuery) ([]domain.Message, error)
+	// RebuildSearchIndex は全メッセージの search_ngrams を作り直し、更新した件数を返す
+	RebuildSearchIndex() (int64, error)
 }
 
 type Message struct {
-	ID        uuid.UUID `db:"id"`
-	Author    string    `db:"author"`
-	Content   string    `db:"message"`
-	ParentID  uuid.UUID `db:"replies_to"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	ID        uuid.UUID     `db:"id"`
+	Author    string        `db:"author"`
+	Content   string        `db:"message"`
+	ParentID  uuid.UUID     `db:"replies_to"`
+	QuoteOf   uuid.UUID     `db:"quote_of"`
+	Status    string        `db:"status"`
+	PublishAt sql.NullTime  `db:"publish_at"`
+	TokenID   uuid.NullUUID `db:"token_id"` // トークンで投稿していなければ NULL
+	CreatedAt time.Time     `db:"created_at"`
+	UpdatedAt time.Time     `db:"updated_at"`
 }
 
-func (r *repositoryImpl) GetMessages(limit, offset int64, username string, includeReplies bool) ([]domain.Message, error) {
+func (m *Message) toDomain() domain.Message {
+	return domain.Message{
+		ID:        m.ID,
+		Author:    m.Author,
+		Content:   m.Content,
+		ParentID:  m.ParentID,
+		QuoteOf:   m.QuoteOf,
+		Status:    domain.MessageStatus(m.Status),
+		PublishAt: m.PublishAt.Time,
+		TokenID:   m.TokenID.UUID,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+func (r *repositoryImpl) GetMessages(limit, offset int64, username string, includeReplies bool, viewer string) ([]domain.Message, error) {
 	var messages []Message
-	query := "SELECT id, author, message, replies_to, created_at, updated_at FROM messages"
-	args := []any{}
+	query := "SELECT id, author, message, replies_to, quote_of, status, publish_at, token_id, created_at, updated_at FROM messages WHERE status = ? AND author NOT IN (" + hiddenAuthorsQuery + ")"
+	args := []any{domain.MessageStatusPublished, viewer, viewer, viewer}
 
 	if username != "" && includeReplies {
-		query += " WHERE author = ?"
+		query += " AND author = ?"
 		args = append(args, username)
 	}
 	if username != "" && !includeReplies {
-		query += " WHERE author = ? AND replies_to = ?"
+		query += " AND author = ? AND replies_to = ?"
 		args = append(args, username, uuid.Nil)
 	}
 	if username == "" && includeReplies {
-		query += " WHERE replies_to = ?"
+		query += " AND replies_to = ?"
 		args = append(args, uuid.Nil)
 	}
 
@@ -55,34 +85,68 @@ func (r *repositoryImpl) GetMessages(limit, offset int64, username string, inclu
 	// domain.Messageに変換して返す
 	domainMessages := make([]domain.Message, len(messages))
 	for i, msg := range messages {
-		domainMessages[i] = domain.Message{
-			ID:        msg.ID,
-			Author:    msg.Author,
-			Content:   msg.Content,
-			ParentID:  msg.ParentID,
-			CreatedAt: msg.CreatedAt,
-			UpdatedAt: msg.UpdatedAt,
-		}
+		domainMessages[i] = msg.toDomain()
 	}
 
 	return domainMessages, nil
 }
 
-func (r *repositoryImpl) CreateMessage(author, content string, parentID uuid.UUID) (*domain.Message, error) {
+func (r *repositoryImpl) CreateMessage(author, content string, parentID, quoteOf uuid.UUID, publishAt time.Time, tokenID uuid.UUID, poll *domain.NewPoll) (*domain.Message, error) {
 	message := &domain.Message{
 		ID:       uuid.Must(uuid.NewV7()),
 		Author:   author,
 		Content:  content,
 		ParentID: parentID,
+		QuoteOf:  quoteOf,
+		Status:   domain.MessageStatusPublished,
+		TokenID:  tokenID,
 	}
 
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tokenIDValue := uuid.NullUUID{UUID: tokenID, Valid: tokenID != uuid.Nil}
+
 	// データベースに保存
-	_, err := r.db.Exec("INSERT INTO messages (id, author, message, replies_to) VALUES (?, ?, ?, ?)",
-		message.ID, message.Author, message.Content, message.ParentID,
-	)
+	if publishAt.IsZero() {
+		_, err = tx.Exec("INSERT INTO messages (id, author, message, replies_to, quote_of, status, token_id, search_ngrams) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			message.ID, message.Author, message.Content, message.ParentID, message.QuoteOf, message.Status, tokenIDValue, searchIndexText(message.Content),
+		)
+	} else {
+		// 予約投稿は公開日時を作成日時にして、公開されたときにタイムラインのその位置に並ぶようにする
+		message.Status = domain.MessageStatusScheduled
+		_, err = tx.Exec("INSERT INTO messages (id, author, message, replies_to, quote_of, status, publish_at, token_id, created_at, search_ngrams) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			message.ID, message.Author, message.Content, message.ParentID, message.QuoteOf, message.Status, publishAt, tokenIDValue, publishAt, searchIndexText(message.Content),
+		)
+	}
 	if err != nil {
 		return nil, err
 	}
+	// 本文中のハッシュタグを保存
+	for i, tag := range domain.ExtractHashtags(message.Content) {
+		_, err := tx.Exec("INSERT INTO message_tags (message_id, tag, position) VALUES (?, ?, ?)", message.ID, tag, i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// 本文中のメンションを保存
+	for _, m := range domain.ExtractMentions(message.Content) {
+		_, err := tx.Exec("INSERT INTO message_mentions (message_id, username, `offset`, length) VALUES (?, ?, ?, ?)", message.ID, m.Username, m.Offset, m.Length)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if poll != nil {
+		if err := insertPoll(tx, message.ID, poll); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 
 	return r.GetMessageByID(message.ID)
 }
@@ -91,7 +155,7 @@ func (r *repositoryImpl) GetMessageByID(id uuid.UUID) (*domain.Message, error) {
 	var message Message
 
 	// データベースからメッセージを取得しmessageに格納
-	err := r.db.Get(&message, "SELECT id, author, message, replies_to, created_at, updated_at FROM messages WHERE id = ?", id)
+	err := r.db.Get(&message, "SELECT id, author, message, replies_to, quote_of, status, publish_at, token_id, created_at, updated_at FROM messages WHERE id = ?", id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, domain.ErrNotFound
@@ -100,19 +164,28 @@ func (r *repositoryImpl) GetMessageByID(id uuid.UUID) (*domain.Message, error) {
 	}
 
 	// domain.Messageに変換して返す
-	return &domain.Message{
-		ID:        message.ID,
-		Author:    message.Author,
-		Content:   message.Content,
-		ParentID:  message.ParentID,
-		CreatedAt: message.CreatedAt,
-		UpdatedAt: message.UpdatedAt,
-	}, nil
+	result := message.toDomain()
+	return &result, nil
+}
+
+func (r *repositoryImpl) SetMessageStatus(id uuid.UUID, from, to domain.MessageStatus) error {
+	res, err := r.db.Exec("UPDATE messages SET status = ? WHERE id = ? AND status = ?", to, id, from)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
 }
 
 func (r *repositoryImpl) GetRepliesByMessageID(messageID uuid.UUID) ([]*domain.Message, error) {
 	var replies []*Message
-	err := r.db.Select(&replies, "SELECT * FROM messages WHERE replies_to = ? ORDER BY created_at DESC", messageID)
+	err := r.db.Select(&replies, "SELECT id, author, message, replies_to, quote_of, status, publish_at, token_id, created_at, updated_at FROM messages WHERE replies_to = ? AND status = ? ORDER BY created_at DESC", messageID, domain.MessageStatusPublished)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, domain.ErrNotFound
@@ -123,15 +196,80 @@ func (r *repositoryImpl) GetRepliesByMessageID(messageID uuid.UUID) ([]*domain.M
 	// Messageをdomain.Messageに変換
 	domainReplies := make([]*domain.Message, len(replies))
 	for i := range replies {
-		domainReplies[i] = &domain.Message{
-			ID:        replies[i].ID,
-			Author:    replies[i].Author,
-			Content:   replies[i].Content,
-			ParentID:  replies[i].ParentID,
-			CreatedAt: replies[i].CreatedAt,
-			UpdatedAt: replies[i].UpdatedAt,
-		}
+		reply := replies[i].toDomain()
+		domainReplies[i] = &reply
 	}
 
 	return domainReplies, nil
 }
+
+func (r *repositoryImpl) SearchMessages(q domain.MessageSearchQuery) ([]domain.Message, error) {
+	against := booleanQuery(q)
+	if against == "" {
+		return []domain.Message{}, nil
+	}
+
+	query := `SELECT id, author, message, replies_to, quote_of, status, publish_at, token_id, created_at, updated_at
+		FROM messages WHERE MATCH(search_ngrams) AGAINST(? IN BOOLEAN MODE) AND status = ? AND author NOT IN (` + hiddenAuthorsQuery + `)`
+	args := []any{against, domain.MessageStatusPublished, q.Viewer, q.Viewer, q.Viewer}
+	if len(q.Authors) > 0 {
+		query += " AND author IN (?)"
+		args = append(args, q.Authors)
+	}
+	if !q.Since.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, q.Since)
+	}
+	if !q.Until.IsZero() {
+		query += " AND created_at < ?"
+		args = append(args, q.Until)
+	}
+	if q.Order == domain.SearchOrderRelevance {
+		query += " ORDER BY MATCH(search_ngrams) AGAINST(? IN BOOLEAN MODE) DESC, created_at DESC"
+		args = append(args, against)
+	} else {
+		query += " ORDER BY created_at DESC"
+	}
+	query += " LIMIT ? OFFSET ?"
+	args = append(args, q.Limit, q.Offset)
+
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var messages []Message
+	if err := r.db.Select(&messages, r.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	domainMessages := make([]domain.Message, len(messages))
+	for i, msg := range messages {
+		domainMessages[i] = msg.toDomain()
+	}
+	return domainMessages, nil
+}
+
+func (r *repositoryImpl) RebuildSearchIndex() (int64, error) {
+	const batchSize = 500
+	var updated int64
+	lastID := ""
+	for {
+		var messages []Message
+		err := r.db.Select(&messages, "SELECT id, author, message, replies_to, quote_of, status, publish_at, token_id, created_at, updated_at FROM messages WHERE id > ? ORDER BY id LIMIT ?", lastID, batchSize)
+		if err != nil {
+			return updated, err
+		}
+		if len(messages) == 0 {
+			return updated, nil
+		}
+		for _, msg := range messages {
+			// updated_at が検索インデックスの更新で変わらないようにそのまま入れ直す
+			_, err := r.db.Exec("UPDATE messages SET search_ngrams = ?, updated_at = updated_at WHERE id = ?", searchIndexText(msg.Content), msg.ID)
+			if err != nil {
+				return updated, err
+			}
+			updated++
+		}
+		lastID = messages[len(messages)-1].ID.String()
+	}
+}
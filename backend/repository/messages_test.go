@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+func TestCreateMessageWithPoll(t *testing.T) {
+	r, db := newTestRepository(t)
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name    string
+		author  string
+		poll    *domain.NewPoll
+		wantErr bool
+	}{
+		{"without poll", "alice", nil, false},
+		{"with poll", "bob", &domain.NewPoll{Options: []string{"yes", "no"}, Multiple: true, ExpiresAt: expiresAt}, false},
+		// 投票を保存できなければメッセージも残らない
+		{"poll fails", "carol", &domain.NewPoll{Options: []string{"yes", strings.Repeat("x", 1000)}, ExpiresAt: expiresAt}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := r.CreateMessage(tt.author, "poll", uuid.Nil, uuid.Nil, time.Time{}, uuid.Nil, tt.poll)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				var count int
+				if err := db.Get(&count, "SELECT COUNT(*) FROM messages WHERE author = ?", tt.author); err != nil {
+					t.Fatal(err)
+				}
+				if count != 0 {
+					t.Fatalf("%d messages were left without their poll", count)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, err := r.GetPoll(msg.ID)
+			if tt.poll == nil {
+				if !errors.Is(err, domain.ErrNotFound) {
+					t.Fatalf("GetPoll = %v, want domain.ErrNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(p.Options) != len(tt.poll.Options) || p.Multiple != tt.poll.Multiple || !p.ExpiresAt.Equal(tt.poll.ExpiresAt) {
+				t.Fatalf("GetPoll = %+v, want %+v", p, tt.poll)
+			}
+			for i, o := range p.Options {
+				if o.Label != tt.poll.Options[i] {
+					t.Fatalf("option %d = %q, want %q", i, o.Label, tt.poll.Options[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCreateMessageStoresTokenID(t *testing.T) {
+	r, db := newTestRepository(t)
+	tokenID := uuid.New()
+
+	tests := []struct {
+		name    string
+		tokenID uuid.UUID
+	}{
+		{"without token", uuid.Nil},
+		{"with token", tokenID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := r.CreateMessage("alice", "token", uuid.Nil, uuid.Nil, time.Time{}, tt.tokenID, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.TokenID != tt.tokenID {
+				t.Fatalf("TokenID = %v, want %v", msg.TokenID, tt.tokenID)
+			}
+			var stored uuid.NullUUID
+			if err := db.Get(&stored, "SELECT token_id FROM messages WHERE id = ?", msg.ID); err != nil {
+				t.Fatal(err)
+			}
+			// トークンを使っていなければ NULL のまま
+			if stored.Valid != (tt.tokenID != uuid.Nil) || stored.UUID != tt.tokenID {
+				t.Fatalf("token_id = %+v, want %v", stored, tt.tokenID)
+			}
+		})
+	}
+}
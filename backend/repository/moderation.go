@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+type ModerationRepository interface {
+	// CreateReport は同じユーザーが既に通報していれば domain.ErrConflict を返す
+	CreateReport(messageID uuid.UUID, reporter, reason string) (*domain.Report, error)
+	// GetReportQueue は未対応の通報があるメッセージを通報の多い順に返す
+	GetReportQueue(limit, offset int64) ([]domain.ReportedMessage, error)
+	GetOpenReports(messageID uuid.UUID) ([]domain.Report, error)
+	// ResolveReports はメッセージの未対応の通報を閉じ、log.Action に応じてメッセージを非表示にするか作者を投稿停止にして記録する
+	// 投稿停止は既に決まっている期限より短くしない
+	// 未対応の通報がなければ domain.ErrNotFound を返す
+	ResolveReports(log domain.ModerationLog) error
+	CreateModerationLog(log domain.ModerationLog) error
+	GetModerationLogs(limit, offset int64) ([]domain.ModerationLog, error)
+	// GetSuspendedUntil は投稿停止の期限を返す。停止されていなければゼロ値を返す
+	GetSuspendedUntil(username string) (time.Time, error)
+}
+
+type repoReport struct {
+	ID        uuid.UUID `db:"id"`
+	MessageID uuid.UUID `db:"message_id"`
+	Reporter  string    `db:"reporter"`
+	Reason    string    `db:"reason"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (r *repoReport) toDomain() domain.Report {
+	return domain.Report{
+		ID:        r.ID,
+		MessageID: r.MessageID,
+		Reporter:  r.Reporter,
+		Reason:    r.Reason,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+type repoModerationLog struct {
+	ID             uuid.UUID    `db:"id"`
+	Moderator      string       `db:"moderator"`
+	Action         string       `db:"action"`
+	MessageID      uuid.UUID    `db:"message_id"`
+	TargetUser     string       `db:"target_user"`
+	SuspendedUntil sql.NullTime `db:"suspended_until"`
+	Note           string       `db:"note"`
+	CreatedAt      time.Time    `db:"created_at"`
+}
+
+func (r *repositoryImpl) CreateReport(messageID uuid.UUID, reporter, reason string) (*domain.Report, error) {
+	id := uuid.Must(uuid.NewV7())
+	res, err := r.db.Exec("INSERT IGNORE INTO message_reports (id, message_id, reporter, reason) VALUES (?, ?, ?, ?)",
+		id, messageID, reporter, reason)
+	if err != nil {
+		return nil, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, domain.ErrConflict
+	}
+
+	var report repoReport
+	err = r.db.Get(&report, "SELECT id, message_id, reporter, reason, created_at FROM message_reports WHERE id = ?", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	result := report.toDomain()
+	return &result, nil
+}
+
+func (r *repositoryImpl) GetReportQueue(limit, offset int64) ([]domain.ReportedMessage, error) {
+	var rows []struct {
+		Message
+		ReportCount    int64     `db:"report_count"`
+		LastReportedAt time.Time `db:"last_reported_at"`
+	}
+	err := r.db.Select(&rows, `SELECT m.id, m.author, m.message, m.replies_to, m.quote_of, m.status, m.publish_at, m.token_id, m.created_at, m.updated_at,
+			q.report_count, q.last_reported_at
+		FROM (
+			SELECT message_id, COUNT(*) AS report_count, MAX(created_at) AS last_reported_at
+			FROM message_reports WHERE resolved_at IS NULL GROUP BY message_id
+		) q JOIN messages m ON m.id = q.message_id
+		ORDER BY q.report_count DESC, q.last_reported_at DESC LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.ReportedMessage, len(rows))
+	for i := range rows {
+		result[i] = domain.ReportedMessage{
+			Message:        rows[i].toDomain(),
+			ReportCount:    rows[i].ReportCount,
+			LastReportedAt: rows[i].LastReportedAt,
+		}
+	}
+	return result, nil
+}
+
+func (r *repositoryImpl) GetOpenReports(messageID uuid.UUID) ([]domain.Report, error) {
+	var reports []repoReport
+	err := r.db.Select(&reports, `SELECT id, message_id, reporter, reason, created_at FROM message_reports
+		WHERE message_id = ? AND resolved_at IS NULL ORDER BY created_at DESC`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.Report, len(reports))
+	for i := range reports {
+		result[i] = reports[i].toDomain()
+	}
+	return result, nil
+}
+
+func (r *repositoryImpl) ResolveReports(log domain.ModerationLog) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("UPDATE message_reports SET resolved_at = CURRENT_TIMESTAMP WHERE message_id = ? AND resolved_at IS NULL", log.MessageID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+
+	switch log.Action {
+	case domain.ModerationActionHide:
+		_, err = tx.Exec("UPDATE messages SET status = ? WHERE id = ? AND status = ?",
+			domain.MessageStatusHidden, log.MessageID, domain.MessageStatusPublished)
+	case domain.ModerationActionSuspend:
+		// 既に長い停止が決まっていれば短くしない (GREATEST は NULL を含むと NULL になるので COALESCE する)
+		_, err = tx.Exec(`INSERT INTO users (username, suspended_until) VALUES (?, ?)
+			ON DUPLICATE KEY UPDATE suspended_until = GREATEST(COALESCE(suspended_until, VALUES(suspended_until)), VALUES(suspended_until))`,
+			log.TargetUser, log.SuspendedUntil)
+	}
+	if err != nil {
+		return err
+	}
+	if err := insertModerationLog(tx, log); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *repositoryImpl) CreateModerationLog(log domain.ModerationLog) error {
+	return insertModerationLog(r.db, log)
+}
+
+func insertModerationLog(e sqlx.Execer, log domain.ModerationLog) error {
+	var suspendedUntil sql.NullTime
+	if !log.SuspendedUntil.IsZero() {
+		suspendedUntil = sql.NullTime{Time: log.SuspendedUntil, Valid: true}
+	}
+	_, err := e.Exec(`INSERT INTO moderation_logs (id, moderator, action, message_id, target_user, suspended_until, note)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		uuid.Must(uuid.NewV7()), log.Moderator, log.Action, log.MessageID, log.TargetUser, suspendedUntil, log.Note)
+	return err
+}
+
+func (r *repositoryImpl) GetModerationLogs(limit, offset int64) ([]domain.ModerationLog, error) {
+	var logs []repoModerationLog
+	err := r.db.Select(&logs, `SELECT id, moderator, action, message_id, target_user, suspended_until, note, created_at
+		FROM moderation_logs ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.ModerationLog, len(logs))
+	for i, l := range logs {
+		result[i] = domain.ModerationLog{
+			ID:             l.ID,
+			Moderator:      l.Moderator,
+			Action:         domain.ModerationAction(l.Action),
+			MessageID:      l.MessageID,
+			TargetUser:     l.TargetUser,
+			SuspendedUntil: l.SuspendedUntil.Time,
+			Note:           l.Note,
+			CreatedAt:      l.CreatedAt,
+		}
+	}
+	return result, nil
+}
+
+func (r *repositoryImpl) GetSuspendedUntil(username string) (time.Time, error) {
+	var until sql.NullTime
+	err := r.db.Get(&until, "SELECT suspended_until FROM users WHERE username = ?", username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return until.Time, nil
+}
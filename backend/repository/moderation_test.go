@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+func TestResolveReportsNeverShortensSuspension(t *testing.T) {
+	r, _ := newTestRepository(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name  string
+		until time.Time
+		want  time.Time
+	}{
+		{"first suspension", now.Add(24 * time.Hour), now.Add(24 * time.Hour)},
+		{"longer suspension", now.Add(48 * time.Hour), now.Add(48 * time.Hour)},
+		{"shorter suspension", now.Add(time.Hour), now.Add(48 * time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := r.CreateMessage("alice", "reported", uuid.Nil, uuid.Nil, time.Time{}, uuid.Nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.CreateReport(msg.ID, "bob", "spam"); err != nil {
+				t.Fatal(err)
+			}
+			err = r.ResolveReports(domain.ModerationLog{
+				Moderator:      "mod",
+				Action:         domain.ModerationActionSuspend,
+				MessageID:      msg.ID,
+				TargetUser:     "alice",
+				SuspendedUntil: tt.until,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.GetSuspendedUntil("alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Fatalf("suspended until %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+type MuteRepository interface {
+	// InsertMute は既にミュートしていれば domain.ErrConflict を返す
+	InsertMute(muter, mutee string) error
+	// DeleteMute はミュートしていなければ domain.ErrNotFound を返す
+	DeleteMute(muter, mutee string) error
+	GetMutes(muter string, limit, offset int64) ([]domain.Mute, error)
+	// InsertBlock は既にブロックしていれば domain.ErrConflict を返す
+	InsertBlock(blocker, blocked string) error
+	// DeleteBlock はブロックしていなければ domain.ErrNotFound を返す
+	DeleteBlock(blocker, blocked string) error
+	GetBlocks(blocker string, limit, offset int64) ([]domain.Block, error)
+	IsBlocking(blocker, blocked string) (bool, error)
+	// GetHiddenUsers は viewer にメッセージを見せないユーザーを返す
+	// viewer がミュート・ブロックしているユーザーと、viewer をブロックしているユーザー
+	GetHiddenUsers(viewer string) ([]string, error)
+}
+
+// hiddenAuthorsQuery は viewer にメッセージを見せないユーザーを返すサブクエリ。引数に viewer を3回渡す
+const hiddenAuthorsQuery = `SELECT mutee FROM mutes WHERE muter = ?
+	UNION SELECT blocked FROM blocks WHERE blocker = ?
+	UNION SELECT blocker FROM blocks WHERE blocked = ?`
+
+func (r *repositoryImpl) InsertMute(muter, mutee string) error {
+	return r.insertRelation("INSERT IGNORE INTO mutes (muter, mutee) VALUES (?, ?)", muter, mutee)
+}
+
+func (r *repositoryImpl) DeleteMute(muter, mutee string) error {
+	return r.deleteRelation("DELETE FROM mutes WHERE muter = ? AND mutee = ?", muter, mutee)
+}
+
+func (r *repositoryImpl) GetMutes(muter string, limit, offset int64) ([]domain.Mute, error) {
+	var mutes []struct {
+		Muter     string    `db:"muter"`
+		Mutee     string    `db:"mutee"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+	err := r.db.Select(&mutes, "SELECT muter, mutee, created_at FROM mutes WHERE muter = ? ORDER BY created_at DESC LIMIT ? OFFSET ?", muter, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.Mute, len(mutes))
+	for i, m := range mutes {
+		result[i] = domain.Mute{
+			Muter:     m.Muter,
+			Mutee:     m.Mutee,
+			CreatedAt: m.CreatedAt,
+		}
+	}
+	return result, nil
+}
+
+func (r *repositoryImpl) InsertBlock(blocker, blocked string) error {
+	return r.insertRelation("INSERT IGNORE INTO blocks (blocker, blocked) VALUES (?, ?)", blocker, blocked)
+}
+
+func (r *repositoryImpl) DeleteBlock(blocker, blocked string) error {
+	return r.deleteRelation("DELETE FROM blocks WHERE blocker = ? AND blocked = ?", blocker, blocked)
+}
+
+func (r *repositoryImpl) GetBlocks(blocker string, limit, offset int64) ([]domain.Block, error) {
+	var blocks []struct {
+		Blocker   string    `db:"blocker"`
+		Blocked   string    `db:"blocked"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+	err := r.db.Select(&blocks, "SELECT blocker, blocked, created_at FROM blocks WHERE blocker = ? ORDER BY created_at DESC LIMIT ? OFFSET ?", blocker, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.Block, len(blocks))
+	for i, b := range blocks {
+		result[i] = domain.Block{
+			Blocker:   b.Blocker,
+			Blocked:   b.Blocked,
+			CreatedAt: b.CreatedAt,
+		}
+	}
+	return result, nil
+}
+
+func (r *repositoryImpl) IsBlocking(blocker, blocked string) (bool, error) {
+	var exists bool
+	err := r.db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM blocks WHERE blocker = ? AND blocked = ?)", blocker, blocked)
+	return exists, err
+}
+
+func (r *repositoryImpl) GetHiddenUsers(viewer string) ([]string, error) {
+	var users []string
+	if err := r.db.Select(&users, hiddenAuthorsQuery, viewer, viewer, viewer); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *repositoryImpl) insertRelation(query, from, to string) error {
+	res, err := r.db.Exec(query, from, to)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrConflict
+	}
+	return nil
+}
+
+func (r *repositoryImpl) deleteRelation(query, from, to string) error {
+	res, err := r.db.Exec(query, from, to)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+func authorsOf(messages []domain.Message) []string {
+	authors := make([]string, len(messages))
+	for i, msg := range messages {
+		authors[i] = msg.Author
+	}
+	slices.Sort(authors)
+	return authors
+}
+
+func TestListsExcludeHiddenAuthors(t *testing.T) {
+	r, _ := newTestRepository(t)
+
+	// alice は bob をミュートしていて、dave は alice をブロックしている
+	if err := r.InsertMute("alice", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.InsertBlock("dave", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	for _, author := range []string{"bob", "carol", "dave"} {
+		msg, err := r.CreateMessage(author, "findme #topic @alice", uuid.Nil, uuid.Nil, time.Time{}, uuid.Nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.PinMessage(msg.ID, author); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"carol"}
+	searched, err := r.SearchMessages(domain.MessageSearchQuery{Terms: []string{"findme"}, Order: domain.SearchOrderRecent, Limit: 10, Viewer: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := authorsOf(searched); !slices.Equal(got, want) {
+		t.Errorf("SearchMessages() authors = %v, want %v", got, want)
+	}
+	tagged, err := r.GetMessagesByTag("topic", 10, 0, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := authorsOf(tagged); !slices.Equal(got, want) {
+		t.Errorf("GetMessagesByTag() authors = %v, want %v", got, want)
+	}
+	mentioning, err := r.GetMessagesMentioning("alice", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := authorsOf(mentioning); !slices.Equal(got, want) {
+		t.Errorf("GetMessagesMentioning() authors = %v, want %v", got, want)
+	}
+
+	for _, tt := range []struct {
+		owner string
+		want  int
+	}{{"bob", 0}, {"carol", 1}, {"dave", 0}} {
+		pinned, err := r.GetPinnedMessages(tt.owner, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pinned) != tt.want {
+			t.Errorf("GetPinnedMessages(%q) = %d messages, want %d", tt.owner, len(pinned), tt.want)
+		}
+	}
+
+	// 他のユーザーにはすべて見える
+	tagged, err = r.GetMessagesByTag("topic", 10, 0, "eve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := authorsOf(tagged); !slices.Equal(got, []string{"bob", "carol", "dave"}) {
+		t.Errorf("GetMessagesByTag() for another viewer authors = %v", got)
+	}
+}
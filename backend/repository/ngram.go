@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"encoding/hex"
+	"strings"
+	"unicode"
+
+	"github.com/traP-jp/h25s_09/domain"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MariaDBのFULLTEXTインデックスにはngramパーサーがないので、
+// 本文をアプリケーション側でbi-gramに分割して messages.search_ngrams に保存する。
+// 各トークンはUTF-8のバイト列を16進数にしたものに tokenPrefix を付けたもので、
+// 日本語でも区切られ、ストップワードにも引っかからない。
+// ASCIIの1文字は16進数で2文字にしかならず innodb_ft_min_token_size (既定値 3) より短いので、
+// 接頭辞を付けてどのトークンも3文字以上にする。
+// 分割の方法を変えたら go run ./cmd/reindex-search で保存済みのメッセージを作り直す。
+
+// tokenPrefix は16進数にならない文字なので、トークンの区切りを変えずに長さだけを伸ばす
+const tokenPrefix = "x"
+
+func token(s string) string {
+	return tokenPrefix + hex.EncodeToString([]byte(s))
+}
+
+func splitWords(s string) []string {
+	s = strings.ToLower(norm.NFKC.String(s))
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// wordTokens は単語をbi-gramに分割する。
+// withTail のときは末尾の1文字もトークンにして、どの文字も何かのトークンの先頭になるようにする
+func wordTokens(word string, withTail bool) []string {
+	runes := []rune(word)
+	tokens := make([]string, 0, len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		tokens = append(tokens, token(string(runes[i:i+2])))
+	}
+	if withTail || len(runes) == 1 {
+		tokens = append(tokens, token(string(runes[len(runes)-1])))
+	}
+	return tokens
+}
+
+// searchIndexText は本文から search_ngrams に保存する文字列を作る
+func searchIndexText(content string) string {
+	var tokens []string
+	for _, w := range splitWords(content) {
+		tokens = append(tokens, wordTokens(w, true)...)
+	}
+	return strings.Join(tokens, " ")
+}
+
+// phraseQuery は語句を BOOLEAN MODE の式に変換する。検索できる文字を含まなければ空文字列を返す
+func phraseQuery(phrase string) string {
+	words := splitWords(phrase)
+	if len(words) == 0 {
+		return ""
+	}
+	// 1文字だけの語句は、その文字で始まるトークンの前方一致で探す
+	if len(words) == 1 && len([]rune(words[0])) == 1 {
+		return wordTokens(words[0], true)[0] + "*"
+	}
+	var tokens []string
+	for i, w := range words {
+		if i == len(words)-1 {
+			// 最後の単語は続きがあってもよいので末尾のトークンを付けない
+			// (1文字の場合はフレーズの式で前方一致できないので省く)
+			if len([]rune(w)) > 1 {
+				tokens = append(tokens, wordTokens(w, false)...)
+			}
+			break
+		}
+		tokens = append(tokens, wordTokens(w, true)...)
+	}
+	return `"` + strings.Join(tokens, " ") + `"`
+}
+
+// booleanQuery は検索条件を MATCH ... AGAINST (... IN BOOLEAN MODE) の式に変換する
+func booleanQuery(q domain.MessageSearchQuery) string {
+	var parts []string
+	for _, t := range q.Terms {
+		if p := phraseQuery(t); p != "" {
+			parts = append(parts, "+"+p)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	for _, t := range q.Excludes {
+		if p := phraseQuery(t); p != "" {
+			parts = append(parts, "-"+p)
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+// innoDBMinTokenSize は innodb_ft_min_token_size の既定値
+const innoDBMinTokenSize = 3
+
+func TestSearchIndexText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"single ascii character", "a", "x61"},
+		{"ascii word", "Go!", "x676f x6f"},
+		{"japanese", "検索", "xe6a49ce7b4a2 xe7b4a2"},
+		{"fullwidth is normalized", "ＡＢ", "x6162 x62"},
+		{"no searchable characters", "!?", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := searchIndexText(tt.content)
+			if got != tt.want {
+				t.Fatalf("searchIndexText(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+			for _, tok := range strings.Fields(got) {
+				if len(tok) < innoDBMinTokenSize {
+					t.Fatalf("token %q is shorter than innodb_ft_min_token_size", tok)
+				}
+			}
+		})
+	}
+}
+
+func TestBooleanQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query domain.MessageSearchQuery
+		want  string
+	}{
+		{"single character", domain.MessageSearchQuery{Terms: []string{"a"}}, "+x61*"},
+		{"word", domain.MessageSearchQuery{Terms: []string{"abc"}}, `+"x6162 x6263"`},
+		{"phrase", domain.MessageSearchQuery{Terms: []string{"ab c"}}, `+"x6162 x62"`},
+		{"exclude", domain.MessageSearchQuery{Terms: []string{"ab"}, Excludes: []string{"cd"}}, `+"x6162" -"x6364"`},
+		{"only excludes", domain.MessageSearchQuery{Excludes: []string{"cd"}}, ""},
+		{"no searchable characters", domain.MessageSearchQuery{Terms: []string{"!!"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := booleanQuery(tt.query); got != tt.want {
+				t.Fatalf("booleanQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+type NotificationRepository interface {
+	// CreateNotification は同じ操作の未読の通知が既にあれば、新しく作らずにその日時を更新する
+	CreateNotification(username string, notificationType domain.NotificationType, actor string, messageID uuid.UUID) (*domain.Notification, error)
+	GetNotifications(username string, limit, offset int64, unreadOnly bool) ([]domain.Notification, error)
+	CountUnreadNotifications(username string) (int64, error)
+	// MarkNotificationRead は username 宛ての通知でなければ domain.ErrNotFound を返す
+	MarkNotificationRead(username string, notificationID uuid.UUID) error
+	MarkAllNotificationsRead(username string) (int64, error)
+}
+
+type repoNotification struct {
+	ID        uuid.UUID `db:"id"`
+	Username  string    `db:"username"`
+	Type      string    `db:"type"`
+	Actor     string    `db:"actor"`
+	MessageID uuid.UUID `db:"message_id"`
+	IsRead    bool      `db:"is_read"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (n *repoNotification) toDomain() domain.Notification {
+	return domain.Notification{
+		ID:        n.ID,
+		Username:  n.Username,
+		Type:      domain.NotificationType(n.Type),
+		Actor:     n.Actor,
+		MessageID: n.MessageID,
+		Read:      n.IsRead,
+		CreatedAt: n.CreatedAt,
+	}
+}
+
+func (r *repositoryImpl) CreateNotification(username string, notificationType domain.NotificationType, actor string, messageID uuid.UUID) (*domain.Notification, error) {
+	id := uuid.Must(uuid.NewV7())
+	// 未読の通知は (username, type, actor, message_id) で一意なので、重なったら既存の行を最新にする
+	_, err := r.db.Exec(`INSERT INTO notifications (id, username, type, actor, message_id) VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE created_at = CURRENT_TIMESTAMP`,
+		id, username, notificationType, actor, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	var n repoNotification
+	err = r.db.Get(&n, `SELECT id, username, type, actor, message_id, is_read, created_at FROM notifications
+		WHERE username = ? AND type = ? AND actor = ? AND message_id = ? ORDER BY created_at DESC, id DESC LIMIT 1`,
+		username, notificationType, actor, messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	result := n.toDomain()
+	return &result, nil
+}
+
+func (r *repositoryImpl) GetNotifications(username string, limit, offset int64, unreadOnly bool) ([]domain.Notification, error) {
+	query := "SELECT id, username, type, actor, message_id, is_read, created_at FROM notifications WHERE username = ?"
+	if unreadOnly {
+		query += " AND is_read = FALSE"
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?"
+
+	var notifications []repoNotification
+	if err := r.db.Select(&notifications, query, username, limit, offset); err != nil {
+		return nil, err
+	}
+	result := make([]domain.Notification, len(notifications))
+	for i := range notifications {
+		result[i] = notifications[i].toDomain()
+	}
+	return result, nil
+}
+
+func (r *repositoryImpl) CountUnreadNotifications(username string) (int64, error) {
+	var count int64
+	err := r.db.Get(&count, "SELECT COUNT(*) FROM notifications WHERE username = ? AND is_read = FALSE", username)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *repositoryImpl) MarkNotificationRead(username string, notificationID uuid.UUID) error {
+	var exists bool
+	err := r.db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM notifications WHERE id = ? AND username = ?)", notificationID, username)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return domain.ErrNotFound
+	}
+	_, err = r.db.Exec("UPDATE notifications SET is_read = TRUE WHERE id = ? AND username = ?", notificationID, username)
+	return err
+}
+
+func (r *repositoryImpl) MarkAllNotificationsRead(username string) (int64, error) {
+	res, err := r.db.Exec("UPDATE notifications SET is_read = TRUE WHERE username = ? AND is_read = FALSE", username)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+func TestCreateNotificationMergesUnread(t *testing.T) {
+	r, _ := newTestRepository(t)
+	msg, err := r.CreateMessage("alice", "hello", uuid.Nil, uuid.Nil, time.Time{}, uuid.Nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := r.CreateNotification("alice", domain.NotificationTypeReaction, "bob", msg.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		markRead   bool // 作る前に全て既読にする
+		actor      string
+		wantMerged bool
+		wantUnread int64
+	}{
+		{"same reaction again", false, "bob", true, 1},
+		{"another actor", false, "carol", false, 2},
+		{"after reading", true, "bob", false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.markRead {
+				if _, err := r.MarkAllNotificationsRead("alice"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			n, err := r.CreateNotification("alice", domain.NotificationTypeReaction, tt.actor, msg.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if merged := n.ID == first.ID; merged != tt.wantMerged {
+				t.Fatalf("merged = %v, want %v", merged, tt.wantMerged)
+			}
+			unread, err := r.CountUnreadNotifications("alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if unread != tt.wantUnread {
+				t.Fatalf("unread = %d, want %d", unread, tt.wantUnread)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+type PinRepository interface {
+	// PinMessage は既に固定していれば domain.ErrConflict を、
+	// 固定数が domain.MaxPinnedMessages に達していれば domain.ErrLimitExceeded を返す
+	PinMessage(messageID uuid.UUID, username string) error
+	// UnpinMessage は username が固定していなければ domain.ErrNotFound を返す
+	UnpinMessage(messageID uuid.UUID, username string) error
+	IsPinned(messageID uuid.UUID) (bool, error)
+	// GetPinnedMessages は username が固定したメッセージを固定した日時の新しい順に返す
+	// GetMessages と同じく viewer に見せないユーザーのメッセージは含めない
+	GetPinnedMessages(username, viewer string) ([]domain.Message, error)
+}
+
+func (r *repositoryImpl) PinMessage(messageID uuid.UUID, username string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 同時に固定されても上限を超えないようにユーザーの行をロックする
+	// まだ行がなければ作ってロックするので、初めての固定が重なっても数え間違えない
+	if _, err := tx.Exec("INSERT INTO users (username) VALUES (?) ON DUPLICATE KEY UPDATE username = username", username); err != nil {
+		return err
+	}
+	// 非表示や取り消しになったメッセージはプロフィールに出ないので数えない
+	var count int64
+	err = tx.Get(&count, `SELECT COUNT(*) FROM pinned_messages p JOIN messages m ON m.id = p.message_id
+		WHERE p.username = ? AND m.status = ?`, username, domain.MessageStatusPublished)
+	if err != nil {
+		return err
+	}
+	res, err := tx.Exec("INSERT IGNORE INTO pinned_messages (message_id, username) VALUES (?, ?)", messageID, username)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrConflict
+	}
+	if count >= domain.MaxPinnedMessages {
+		return domain.ErrLimitExceeded
+	}
+	return tx.Commit()
+}
+
+func (r *repositoryImpl) UnpinMessage(messageID uuid.UUID, username string) error {
+	res, err := r.db.Exec("DELETE FROM pinned_messages WHERE message_id = ? AND username = ?", messageID, username)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repositoryImpl) IsPinned(messageID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.Get(&exists, "SELECT TRUE FROM pinned_messages WHERE message_id = ?", messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return exists, nil
+}
+
+func (r *repositoryImpl) GetPinnedMessages(username, viewer string) ([]domain.Message, error) {
+	var messages []Message
+	err := r.db.Select(&messages, `SELECT m.id, m.author, m.message, m.replies_to, m.quote_of, m.status, m.publish_at, m.token_id, m.created_at, m.updated_at
+		FROM pinned_messages p JOIN messages m ON m.id = p.message_id
+		WHERE p.username = ? AND m.status = ? AND m.author NOT IN (`+hiddenAuthorsQuery+`)
+		ORDER BY p.pinned_at DESC`, username, domain.MessageStatusPublished, viewer, viewer, viewer)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.Message, len(messages))
+	for i := range messages {
+		result[i] = messages[i].toDomain()
+	}
+	return result, nil
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+func TestPinMessageLimit(t *testing.T) {
+	r, _ := newTestRepository(t)
+
+	newMessage := func() uuid.UUID {
+		t.Helper()
+		msg, err := r.CreateMessage("alice", "pinned", uuid.Nil, uuid.Nil, time.Time{}, uuid.Nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msg.ID
+	}
+
+	// まだ users に行のないユーザーが同時に固定しても上限を超えない
+	var wg sync.WaitGroup
+	errs := make([]error, domain.MaxPinnedMessages+2)
+	for i := range errs {
+		messageID := newMessage()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = r.PinMessage(messageID, "carol")
+		}()
+	}
+	wg.Wait()
+	pinned := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			pinned++
+		case !errors.Is(err, domain.ErrLimitExceeded):
+			t.Fatal(err)
+		}
+	}
+	if pinned != domain.MaxPinnedMessages {
+		t.Fatalf("pinned %d messages concurrently, want %d", pinned, domain.MaxPinnedMessages)
+	}
+
+	var messageIDs []uuid.UUID
+	for range domain.MaxPinnedMessages {
+		messageID := newMessage()
+		if err := r.PinMessage(messageID, "alice"); err != nil {
+			t.Fatal(err)
+		}
+		messageIDs = append(messageIDs, messageID)
+	}
+	if err := r.PinMessage(newMessage(), "alice"); !errors.Is(err, domain.ErrLimitExceeded) {
+		t.Fatalf("pinning over the limit = %v, want domain.ErrLimitExceeded", err)
+	}
+	// 非表示になったメッセージの固定は上限に数えない
+	if err := r.SetMessageStatus(messageIDs[0], domain.MessageStatusPublished, domain.MessageStatusHidden); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.PinMessage(newMessage(), "alice"); err != nil {
+		t.Fatalf("pinning after a pinned message was hidden = %v", err)
+	}
+}
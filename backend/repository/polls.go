@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+type PollRepository interface {
+	// GetPoll は投票が付いていなければ domain.ErrNotFound を返す
+	GetPoll(messageID uuid.UUID) (*domain.Poll, error)
+	// GetMyPollVotes は username が選んだ選択肢の番号を返す。投票していなければ空
+	GetMyPollVotes(messageID uuid.UUID, username string) ([]int, error)
+	// VotePoll は既に投票していれば domain.ErrConflict を返す
+	VotePoll(messageID uuid.UUID, username string, choices []int) error
+}
+
+type repoPoll struct {
+	MessageID uuid.UUID `db:"message_id"`
+	Multiple  bool      `db:"multiple"`
+	ExpiresAt time.Time `db:"expires_at"`
+	Voters    int64     `db:"voters"`
+}
+
+type repoPollOption struct {
+	Index int    `db:"option_index"`
+	Label string `db:"label"`
+	Votes int64  `db:"votes"`
+}
+
+// insertPoll はメッセージの投票を保存する。CreateMessage のトランザクションの中で呼ぶ
+func insertPoll(tx sqlx.Execer, messageID uuid.UUID, poll *domain.NewPoll) error {
+	_, err := tx.Exec("INSERT INTO polls (message_id, multiple, expires_at) VALUES (?, ?, ?)", messageID, poll.Multiple, poll.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	for i, label := range poll.Options {
+		_, err := tx.Exec("INSERT INTO poll_options (message_id, option_index, label) VALUES (?, ?, ?)", messageID, i, label)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetPoll(messageID uuid.UUID) (*domain.Poll, error) {
+	var p repoPoll
+	err := r.db.Get(&p, `SELECT message_id, multiple, expires_at,
+		(SELECT COUNT(*) FROM poll_ballots WHERE message_id = polls.message_id) AS voters
+		FROM polls WHERE message_id = ?`, messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+
+	var options []repoPollOption
+	err = r.db.Select(&options, `SELECT o.option_index, o.label, COUNT(v.username) AS votes
+		FROM poll_options o LEFT JOIN poll_votes v ON v.message_id = o.message_id AND v.option_index = o.option_index
+		WHERE o.message_id = ? GROUP BY o.option_index, o.label ORDER BY o.option_index`, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	poll := &domain.Poll{
+		MessageID: p.MessageID,
+		Multiple:  p.Multiple,
+		ExpiresAt: p.ExpiresAt,
+		Options:   make([]domain.PollOption, len(options)),
+		Voters:    p.Voters,
+	}
+	for i, o := range options {
+		poll.Options[i] = domain.PollOption{
+			Index: o.Index,
+			Label: o.Label,
+			Votes: o.Votes,
+		}
+	}
+	return poll, nil
+}
+
+func (r *repositoryImpl) GetMyPollVotes(messageID uuid.UUID, username string) ([]int, error) {
+	votes := []int{}
+	err := r.db.Select(&votes, "SELECT option_index FROM poll_votes WHERE message_id = ? AND username = ? ORDER BY option_index", messageID, username)
+	if err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+func (r *repositoryImpl) VotePoll(messageID uuid.UUID, username string, choices []int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// poll_ballots の主キーで1人1回に制限する
+	res, err := tx.Exec("INSERT IGNORE INTO poll_ballots (message_id, username) VALUES (?, ?)", messageID, username)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrConflict
+	}
+	for _, choice := range choices {
+		_, err := tx.Exec("INSERT INTO poll_votes (message_id, username, option_index) VALUES (?, ?, ?)", messageID, username, choice)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
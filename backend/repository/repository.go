@@ -7,6 +7,19 @@ type Repository interface {
 	MessageRepository
 	MessageReactionRepository
 	MessageImageRepository
+	MessageTagRepository
+	MessageMentionRepository
+	NotificationRepository
+	FollowRepository
+	UserRepository
+	RepostRepository
+	BookmarkRepository
+	PinRepository
+	ScheduledMessageRepository
+	PollRepository
+	TokenRepository
+	ModerationRepository
+	MuteRepository
 }
 
 type repositoryImpl struct {
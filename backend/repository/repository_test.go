@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// newTestRepository は db/init/0_Schema.sql を流した使い捨てのデータベースにつなぐ
+// TEST_MARIADB_DSN (例: root:password@tcp(localhost:3306)/) が設定されていなければテストを飛ばす
+func newTestRepository(t *testing.T) (*repositoryImpl, *sqlx.DB) {
+	t.Helper()
+	dsn := os.Getenv("TEST_MARIADB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MARIADB_DSN is not set")
+	}
+	config, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ParseTime = true
+	config.MultiStatements = true
+	config.Collation = "utf8mb4_general_ci"
+
+	admin, err := sqlx.Connect("mysql", config.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	name := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP DATABASE " + name) })
+
+	schema, err := os.ReadFile("../db/init/0_Schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.DBName = name
+	db, err := sqlx.Connect("mysql", config.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(string(schema)); err != nil {
+		t.Fatal(fmt.Errorf("failed to load schema: %w", err))
+	}
+	return &repositoryImpl{db: db}, db
+}
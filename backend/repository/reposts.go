@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+type RepostRepository interface {
+	// InsertRepost は既にリポストしていれば domain.ErrConflict を返す
+	InsertRepost(messageID uuid.UUID, username string) error
+	// DeleteRepost はリポストしていなければ domain.ErrNotFound を返す
+	DeleteRepost(messageID uuid.UUID, username string) error
+	GetRepostsOfMessage(messageID uuid.UUID) ([]*domain.Repost, error)
+}
+
+type repoRepost struct {
+	MessageID uuid.UUID `db:"message_id"`
+	Username  string    `db:"username"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (r *repositoryImpl) InsertRepost(messageID uuid.UUID, username string) error {
+	res, err := r.db.Exec("INSERT IGNORE INTO reposts (message_id, username) VALUES (?, ?)", messageID, username)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrConflict
+	}
+	return nil
+}
+
+func (r *repositoryImpl) DeleteRepost(messageID uuid.UUID, username string) error {
+	res, err := r.db.Exec("DELETE FROM reposts WHERE message_id = ? AND username = ?", messageID, username)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetRepostsOfMessage(messageID uuid.UUID) ([]*domain.Repost, error) {
+	var reposts []repoRepost
+	err := r.db.Select(&reposts, "SELECT message_id, username, created_at FROM reposts WHERE message_id = ?", messageID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*domain.Repost, len(reposts))
+	for i, rp := range reposts {
+		result[i] = &domain.Repost{
+			MessageID: rp.MessageID,
+			Username:  rp.Username,
+			CreatedAt: rp.CreatedAt,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+type ScheduledMessageRepository interface {
+	// GetScheduledMessages は author の公開待ちの予約投稿を公開日時の早い順に返す
+	GetScheduledMessages(author string) ([]domain.Message, error)
+	// CancelScheduledMessage は author の公開待ちの予約投稿でなければ domain.ErrNotFound を返す
+	CancelScheduledMessage(messageID uuid.UUID, author string) error
+	// GetDueScheduledMessages は now までに公開日時を迎えた予約投稿を返す
+	GetDueScheduledMessages(now time.Time) ([]domain.Message, error)
+	// PublishScheduledMessage は予約投稿を公開する
+	// 取り消された・他で既に公開されたなどで公開しなかった場合は false を返す
+	PublishScheduledMessage(messageID uuid.UUID) (bool, error)
+	// GetNextPublishAt は次に公開される予約投稿の公開日時を返す。なければゼロ値を返す
+	GetNextPublishAt() (time.Time, error)
+}
+
+func (r *repositoryImpl) GetScheduledMessages(author string) ([]domain.Message, error) {
+	var messages []Message
+	err := r.db.Select(&messages, `SELECT id, author, message, replies_to, quote_of, status, publish_at, token_id, created_at, updated_at
+		FROM messages WHERE author = ? AND status = ? ORDER BY publish_at, id`, author, domain.MessageStatusScheduled)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.Message, len(messages))
+	for i := range messages {
+		result[i] = messages[i].toDomain()
+	}
+	return result, nil
+}
+
+func (r *repositoryImpl) CancelScheduledMessage(messageID uuid.UUID, author string) error {
+	res, err := r.db.Exec("UPDATE messages SET status = ? WHERE id = ? AND author = ? AND status = ?",
+		domain.MessageStatusCanceled, messageID, author, domain.MessageStatusScheduled)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetDueScheduledMessages(now time.Time) ([]domain.Message, error) {
+	var messages []Message
+	err := r.db.Select(&messages, `SELECT id, author, message, replies_to, quote_of, status, publish_at, token_id, created_at, updated_at
+		FROM messages WHERE status = ? AND publish_at <= ? ORDER BY publish_at, id`, domain.MessageStatusScheduled, now)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.Message, len(messages))
+	for i := range messages {
+		result[i] = messages[i].toDomain()
+	}
+	return result, nil
+}
+
+func (r *repositoryImpl) PublishScheduledMessage(messageID uuid.UUID) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// status を条件にして更新するので、同時に公開しようとしても公開されるのは1回だけ
+	res, err := tx.Exec("UPDATE messages SET status = ? WHERE id = ? AND status = ?",
+		domain.MessageStatusPublished, messageID, domain.MessageStatusScheduled)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+	// トレンドの集計に公開した時刻で入るようにする
+	_, err = tx.Exec("UPDATE message_tags SET created_at = (SELECT publish_at FROM messages WHERE id = ?) WHERE message_id = ?", messageID, messageID)
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *repositoryImpl) GetNextPublishAt() (time.Time, error) {
+	var next sql.NullTime
+	err := r.db.Get(&next, "SELECT MIN(publish_at) FROM messages WHERE status = ?", domain.MessageStatusScheduled)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return next.Time, nil
+}
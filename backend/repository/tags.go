@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+type MessageTagRepository interface {
+	GetTagsByMessageID(messageID uuid.UUID) ([]string, error)
+	// GetMessagesByTag は GetMessages と同じく viewer に見せないユーザーのメッセージを含めない
+	GetMessagesByTag(tag string, limit, offset int64, viewer string) ([]domain.Message, error)
+	// GetTrendingTags は since 以降に使われた回数の多いタグを返す
+	GetTrendingTags(since time.Time, limit int64) ([]domain.TagCount, error)
+}
+
+type repoTagCount struct {
+	Tag   string `db:"tag"`
+	Count int64  `db:"count"`
+}
+
+func (r *repositoryImpl) GetTagsByMessageID(messageID uuid.UUID) ([]string, error) {
+	tags := []string{}
+	err := r.db.Select(&tags, "SELECT tag FROM message_tags WHERE message_id = ? ORDER BY position", messageID)
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *repositoryImpl) GetMessagesByTag(tag string, limit, offset int64, viewer string) ([]domain.Message, error) {
+	var messages []Message
+	err := r.db.Select(&messages, `SELECT m.id, m.author, m.message, m.replies_to, m.quote_of, m.status, m.publish_at, m.token_id, m.created_at, m.updated_at
+		FROM message_tags t JOIN messages m ON m.id = t.message_id
+		WHERE t.tag = ? AND m.status = ? AND m.author NOT IN (`+hiddenAuthorsQuery+`)
+		ORDER BY t.created_at DESC LIMIT ? OFFSET ?`, domain.NormalizeTag(tag), domain.MessageStatusPublished, viewer, viewer, viewer, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	domainMessages := make([]domain.Message, len(messages))
+	for i, msg := range messages {
+		domainMessages[i] = msg.toDomain()
+	}
+	return domainMessages, nil
+}
+
+func (r *repositoryImpl) GetTrendingTags(since time.Time, limit int64) ([]domain.TagCount, error) {
+	var counts []repoTagCount
+	err := r.db.Select(&counts, `SELECT t.tag, COUNT(*) AS count FROM message_tags t JOIN messages m ON m.id = t.message_id
+		WHERE t.created_at >= ? AND m.status = ? GROUP BY t.tag ORDER BY count DESC, MAX(t.created_at) DESC LIMIT ?`, since, domain.MessageStatusPublished, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.TagCount, len(counts))
+	for i, c := range counts {
+		result[i] = domain.TagCount{
+			Tag:   c.Tag,
+			Count: c.Count,
+		}
+	}
+	return result, nil
+}
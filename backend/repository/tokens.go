@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+type TokenRepository interface {
+	// CreateToken は tokenHash をハッシュにしたトークンを保存する
+	CreateToken(username, name string, scopes []domain.TokenScope, tokenHash string) (*domain.PersonalAccessToken, error)
+	// GetTokens は username の取り消されていないトークンを新しい順に返す
+	GetTokens(username string) ([]domain.PersonalAccessToken, error)
+	// GetTokenByHash は取り消されていないトークンを返す。なければ domain.ErrNotFound を返す
+	GetTokenByHash(tokenHash string) (*domain.PersonalAccessToken, error)
+	// RevokeToken は username の取り消されていないトークンでなければ domain.ErrNotFound を返す
+	RevokeToken(id uuid.UUID, username string) error
+	TouchToken(id uuid.UUID, usedAt time.Time) error
+}
+
+type repoToken struct {
+	ID         uuid.UUID    `db:"id"`
+	Username   string       `db:"username"`
+	Name       string       `db:"name"`
+	Scopes     string       `db:"scopes"`
+	CreatedAt  time.Time    `db:"created_at"`
+	LastUsedAt sql.NullTime `db:"last_used_at"`
+}
+
+func (t *repoToken) toDomain() domain.PersonalAccessToken {
+	scopes := []domain.TokenScope{}
+	for _, s := range strings.Split(t.Scopes, ",") {
+		if s != "" {
+			scopes = append(scopes, domain.TokenScope(s))
+		}
+	}
+	return domain.PersonalAccessToken{
+		ID:         t.ID,
+		Username:   t.Username,
+		Name:       t.Name,
+		Scopes:     scopes,
+		CreatedAt:  t.CreatedAt,
+		LastUsedAt: t.LastUsedAt.Time,
+	}
+}
+
+func (r *repositoryImpl) CreateToken(username, name string, scopes []domain.TokenScope, tokenHash string) (*domain.PersonalAccessToken, error) {
+	id := uuid.Must(uuid.NewV7())
+	s := make([]string, len(scopes))
+	for i, scope := range scopes {
+		s[i] = string(scope)
+	}
+	_, err := r.db.Exec("INSERT INTO personal_access_tokens (id, username, name, token_hash, scopes) VALUES (?, ?, ?, ?, ?)",
+		id, username, name, tokenHash, strings.Join(s, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	var t repoToken
+	err = r.db.Get(&t, "SELECT id, username, name, scopes, created_at, last_used_at FROM personal_access_tokens WHERE id = ?", id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	result := t.toDomain()
+	return &result, nil
+}
+
+func (r *repositoryImpl) GetTokens(username string) ([]domain.PersonalAccessToken, error) {
+	var tokens []repoToken
+	err := r.db.Select(&tokens, `SELECT id, username, name, scopes, created_at, last_used_at FROM personal_access_tokens
+		WHERE username = ? AND revoked_at IS NULL ORDER BY created_at DESC, id DESC`, username)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]domain.PersonalAccessToken, len(tokens))
+	for i := range tokens {
+		result[i] = tokens[i].toDomain()
+	}
+	return result, nil
+}
+
+func (r *repositoryImpl) GetTokenByHash(tokenHash string) (*domain.PersonalAccessToken, error) {
+	var t repoToken
+	err := r.db.Get(&t, `SELECT id, username, name, scopes, created_at, last_used_at FROM personal_access_tokens
+		WHERE token_hash = ? AND revoked_at IS NULL`, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	result := t.toDomain()
+	return &result, nil
+}
+
+func (r *repositoryImpl) RevokeToken(id uuid.UUID, username string) error {
+	res, err := r.db.Exec("UPDATE personal_access_tokens SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND username = ? AND revoked_at IS NULL", id, username)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repositoryImpl) TouchToken(id uuid.UUID, usedAt time.Time) error {
+	_, err := r.db.Exec("UPDATE personal_access_tokens SET last_used_at = ? WHERE id = ?", usedAt, id)
+	return err
+}
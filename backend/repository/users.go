@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+type UserRepository interface {
+	// EnsureUser はユーザーが存在しなければ作成する
+	EnsureUser(username string) error
+	GetUser(username string) (*domain.User, error)
+	UpdateUserProfile(username, displayName, bio string) (*domain.User, error)
+	GetUserStats(username string) (*domain.UserStats, error)
+	// GetUserRole はユーザーが存在しなければ domain.RoleUser を返す
+	GetUserRole(username string) (domain.Role, error)
+	SetUserRole(username string, role domain.Role) error
+}
+
+type repoUser struct {
+	Username    string    `db:"username"`
+	DisplayName string    `db:"display_name"`
+	Bio         string    `db:"bio"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+func (r *repositoryImpl) EnsureUser(username string) error {
+	_, err := r.db.Exec("INSERT IGNORE INTO users (username) VALUES (?)", username)
+	return err
+}
+
+func (r *repositoryImpl) GetUser(username string) (*domain.User, error) {
+	var user repoUser
+	err := r.db.Get(&user, "SELECT username, display_name, bio, created_at, updated_at FROM users WHERE username = ?", username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &domain.User{
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+	}, nil
+}
+
+func (r *repositoryImpl) UpdateUserProfile(username, displayName, bio string) (*domain.User, error) {
+	_, err := r.db.Exec(`INSERT INTO users (username, display_name, bio) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE display_name = VALUES(display_name), bio = VALUES(bio)`, username, displayName, bio)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetUser(username)
+}
+
+func (r *repositoryImpl) GetUserStats(username string) (*domain.UserStats, error) {
+	var stats struct {
+		PostCount         int64        `db:"post_count"`
+		ReplyCount        int64        `db:"reply_count"`
+		ReactionsReceived int64        `db:"reactions_received"`
+		ReactionsGiven    int64        `db:"reactions_given"`
+		AchievementsCount int64        `db:"achievements_count"`
+		FirstPostAt       sql.NullTime `db:"first_post_at"`
+		LastPostAt        sql.NullTime `db:"last_post_at"`
+	}
+	err := r.db.Get(&stats, `SELECT
+		(SELECT COUNT(*) FROM messages WHERE author = ? AND status = ? AND replies_to = ?) AS post_count,
+		(SELECT COUNT(*) FROM messages WHERE author = ? AND status = ? AND replies_to <> ?) AS reply_count,
+		(SELECT COUNT(*) FROM message_reactions mr JOIN messages m ON m.id = mr.message_id WHERE m.author = ? AND m.status = ?) AS reactions_received,
+		(SELECT COUNT(*) FROM message_reactions WHERE username = ?) AS reactions_given,
+		(SELECT COUNT(*) FROM achievements WHERE username = ?) AS achievements_count,
+		(SELECT MIN(created_at) FROM messages WHERE author = ? AND status = ?) AS first_post_at,
+		(SELECT MAX(created_at) FROM messages WHERE author = ? AND status = ?) AS last_post_at`,
+		username, domain.MessageStatusPublished, uuid.Nil,
+		username, domain.MessageStatusPublished, uuid.Nil,
+		username, domain.MessageStatusPublished, username, username,
+		username, domain.MessageStatusPublished,
+		username, domain.MessageStatusPublished)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.UserStats{
+		PostCount:         stats.PostCount,
+		ReplyCount:        stats.ReplyCount,
+		ReactionsReceived: stats.ReactionsReceived,
+		ReactionsGiven:    stats.ReactionsGiven,
+		AchievementsCount: stats.AchievementsCount,
+	}
+	if stats.FirstPostAt.Valid {
+		result.FirstPostAt = &stats.FirstPostAt.Time
+	}
+	if stats.LastPostAt.Valid {
+		result.LastPostAt = &stats.LastPostAt.Time
+	}
+	return result, nil
+}
+
+func (r *repositoryImpl) GetUserRole(username string) (domain.Role, error) {
+	var role string
+	err := r.db.Get(&role, "SELECT role FROM users WHERE username = ?", username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.RoleUser, nil
+		}
+		return "", err
+	}
+	return domain.Role(role), nil
+}
+
+func (r *repositoryImpl) SetUserRole(username string, role domain.Role) error {
+	_, err := r.db.Exec("INSERT INTO users (username, role) VALUES (?, ?) ON DUPLICATE KEY UPDATE role = VALUES(role)", username, role)
+	return err
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/traP-jp/h25s_09/domain"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewLocalStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("image data")
+	if err := store.Put(ctx, "abcdef", bytes.NewReader(data), int64(len(data)), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ab", "abcdef")); err != nil {
+		t.Fatalf("blob is not stored under its prefix directory: %v", err)
+	}
+
+	rc, err := store.Get(ctx, "abcdef")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("Get = %q, want %q", got, data)
+	}
+
+	if err := store.Delete(ctx, "abcdef"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, "abcdef"); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("Get after Delete = %v, want domain.ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "abcdef"); err != nil {
+		t.Fatalf("Delete missing key: %v", err)
+	}
+}
+
+func TestLocalStoreRejectsInvalidKeys(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"", ".", "..", "../escape", "a/b", `a\b`} {
+		t.Run(key, func(t *testing.T) {
+			if err := store.Put(context.Background(), key, strings.NewReader("x"), 1, "image/png"); err == nil {
+				t.Fatalf("Put(%q) succeeded", key)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// BlobStore は画像などのバイナリをキー単位で保存するストレージ
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get は key が存在しない場合 domain.ErrNotFound を返す
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// NewBlobStore は環境変数 BLOB_STORE の値に応じたBlobStoreを返す
+func NewBlobStore() (BlobStore, error) {
+	switch backend := cmp.Or(os.Getenv("BLOB_STORE"), BackendLocal); backend {
+	case BackendLocal:
+		return NewLocalStore(cmp.Or(os.Getenv("BLOB_LOCAL_DIR"), "data/blobs"))
+	case BackendS3:
+		return NewS3Store(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			UseSSL:          os.Getenv("S3_USE_SSL") != "false",
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob store: %s", backend)
+	}
+}